@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestReference checks that docs/reference is up to date with the commands, run "make docs" to update it.
+func TestReference(t *testing.T) {
+	out := t.TempDir()
+	code, err := root.Run([]string{"gen-docs", "--format", "markdown", "--out", out})
+	if err != nil || code != 0 {
+		t.Fatalf("gen-docs = %d, %v; want success", code, err)
+	}
+
+	reference := filepath.Join("..", "..", "docs", "reference")
+	want, err := os.ReadDir(reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadDir(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("gen-docs wrote %d pages; want the %d pages of docs/reference", len(got), len(want))
+	}
+
+	for _, entry := range got {
+		generated, err := os.ReadFile(filepath.Join(out, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		committed, err := os.ReadFile(filepath.Join(reference, entry.Name()))
+		if err != nil || string(committed) != string(generated) {
+			t.Errorf("docs/reference/%s is outdated, run \"make docs\"", entry.Name())
+		}
+	}
+}
@@ -1,13 +1,63 @@
 package command
 
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sotvokun/emit/internal/pkg/alflag"
+	"github.com/sotvokun/emit/pkg/degit"
+)
+
 const (
 	ExitCodeSuccess = iota
 	ExitCodeArgumentError
 	ExitCodeInternalError
+	// ExitCodeConflict is returned by the update when some files have conflicts to resolve
+	ExitCodeConflict
 )
 
+// EnvPrefix is the prefix of the environment variables of the options, e.g. "EMIT_DEGIT_DRY_RUN" is bound to
+// the "--dry-run" option of the degit command.
+const EnvPrefix = "EMIT"
+
 type Command interface {
 	Name() string
+	// Description is the summary of the command in one line, shown in the usage of its group
+	Description() string
 	Usage() string
+	// FlagSet returns the flags of the command, the flag set of a subcommand inherits the persistent flags of
+	// its group
+	FlagSet() *alflag.FlagSet
 	Run(args []string) (int, error)
 }
+
+// envPrefix returns the prefix of the environment variables of the options of the command.
+func envPrefix(command string) string {
+	return EnvPrefix + "_" + strings.ToUpper(command)
+}
+
+// logEnvSources logs the options which are set from the environment variables. The values are not logged,
+// since they may be secrets.
+func logEnvSources(logger degit.Logger, flagset *alflag.FlagSet) {
+	for _, fl := range flagset.Flags() {
+		name, display := fl.Long, "--"+fl.Long
+		if fl.Long == "" {
+			name, display = fl.Short, "-"+fl.Short
+		}
+		if source, env := flagset.Source(name); source == alflag.SourceEnv {
+			logger.Printf("option %s is set from the environment variable %s", display, env)
+		}
+	}
+}
+
+// parseError prints the error of the arguments returned by [alflag.FlagSet.Parse], and returns the exit code.
+func parseError(err error) (int, error) {
+	var argErr *alflag.ArgumentError
+	if errors.As(err, &argErr) {
+		fmt.Fprintf(os.Stderr, "emit: %v\n", err)
+		return ExitCodeArgumentError, nil
+	}
+	return ExitCodeInternalError, err
+}
@@ -0,0 +1,241 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/sotvokun/emit/internal/pkg/alflag"
+)
+
+// CompleteCommandName is the name of the hidden command called by the completion scripts.
+const CompleteCommandName = "__complete"
+
+// Directive tells the completion scripts how to complete the current word besides the candidates.
+type Directive int
+
+const (
+	// DirectiveFiles completes the file paths
+	DirectiveFiles Directive = 1 << iota
+)
+
+// Completer is implemented by the commands which complete their positional arguments.
+type Completer interface {
+	// CompleteArgs returns the candidates of the word following the positional arguments args.
+	CompleteArgs(args []string, word string) ([]string, Directive)
+}
+
+// hidden is implemented by the commands which are not listed in the usage of their group.
+type hidden interface {
+	Hidden() bool
+}
+
+func isHidden(command Command) bool {
+	h, ok := command.(hidden)
+	return ok && h.Hidden()
+}
+
+// Complete returns the candidates of the last word of the arguments of the command, the last word is empty if a
+// new word is completed.
+func Complete(command Command, args []string) ([]string, Directive) {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	words, word := args[:len(args)-1], args[len(args)-1]
+	flagset := command.FlagSet()
+	group, isGroup := command.(*Group)
+
+	var positionals []string
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if w == "--" {
+			positionals = append(positionals, words[i+1:]...)
+			return completeArgs(command, positionals, word)
+		}
+		if len(w) > 1 && w[0] == '-' {
+			if fl := valueFlag(flagset, w); fl != nil {
+				if i+1 == len(words) {
+					return completeValue(*fl, "", word)
+				}
+				i++
+			}
+			continue
+		}
+
+		if !isGroup {
+			positionals = append(positionals, w)
+			continue
+		}
+		if w == HelpCommandName {
+			return completeHelp(group, words[i+1:], word)
+		}
+		if sub := group.Lookup(w); sub != nil {
+			return Complete(sub, append(slices.Clone(words[i+1:]), word))
+		}
+		return nil, 0
+	}
+
+	if len(word) > 1 && word[0] == '-' || word == "-" {
+		return completeFlag(flagset, word)
+	}
+	return completeArgs(command, positionals, word)
+}
+
+// completeFlag returns the flags starting with the word, or the values of the flag for the word like
+// "--mode=ta".
+func completeFlag(flagset *alflag.FlagSet, word string) ([]string, Directive) {
+	flags := append(flagset.Flags(), flagset.InheritedFlags()...)
+	if name, value, ok := strings.Cut(word, "="); ok && strings.HasPrefix(name, "--") {
+		for _, fl := range flags {
+			if "--"+fl.Long == name {
+				return completeValue(fl, name+"=", value)
+			}
+		}
+		return nil, 0
+	}
+
+	var candidates []string
+	for _, fl := range flags {
+		if fl.Long != "" && strings.HasPrefix("--"+fl.Long, word) {
+			candidates = append(candidates, "--"+fl.Long)
+		}
+		if fl.Short != "" && strings.HasPrefix("-"+fl.Short, word) {
+			candidates = append(candidates, "-"+fl.Short)
+		}
+	}
+	return candidates, 0
+}
+
+// completeValue returns the values of the flag starting with the word, the candidates are prefixed.
+func completeValue(fl alflag.Flag, prefix string, word string) ([]string, Directive) {
+	if fl.Filename {
+		return nil, DirectiveFiles
+	}
+	var candidates []string
+	for _, choice := range fl.Enum {
+		if strings.HasPrefix(choice, word) {
+			candidates = append(candidates, prefix+choice)
+		}
+	}
+	return candidates, 0
+}
+
+// completeArgs returns the subcommands of a group, or the positional arguments completed by the command.
+func completeArgs(command Command, positionals []string, word string) ([]string, Directive) {
+	if group, ok := command.(*Group); ok {
+		candidates := completeCommands(group, word)
+		if strings.HasPrefix(HelpCommandName, word) {
+			candidates = append(candidates, HelpCommandName)
+		}
+		return candidates, 0
+	}
+	if completer, ok := command.(Completer); ok {
+		return completer.CompleteArgs(positionals, word)
+	}
+	return nil, 0
+}
+
+// completeHelp returns the subcommands of the group at the path, e.g. "emit help cache <word>".
+func completeHelp(group *Group, path []string, word string) ([]string, Directive) {
+	for _, name := range path {
+		sub, ok := group.Lookup(name).(*Group)
+		if !ok {
+			return nil, 0
+		}
+		group = sub
+	}
+	return completeCommands(group, word), 0
+}
+
+// completeCommands returns the subcommands of the group starting with the word, except the hidden ones.
+func completeCommands(group *Group, word string) []string {
+	var candidates []string
+	for _, command := range group.Commands() {
+		if !isHidden(command) && strings.HasPrefix(command.Name(), word) {
+			candidates = append(candidates, command.Name())
+		}
+	}
+	return candidates
+}
+
+// valueFlag returns the flag of the word whose value is the next word, e.g. "-i" or "--lock-file", nil is
+// returned if the flag has no value or the value is attached, e.g. "--output=file" or "-vifile".
+func valueFlag(flagset *alflag.FlagSet, word string) *alflag.Flag {
+	flags := append(flagset.Flags(), flagset.InheritedFlags()...)
+	if long, ok := strings.CutPrefix(word, "--"); ok {
+		for _, fl := range flags {
+			if fl.Long == long && fl.Placeholder != "" {
+				return &fl
+			}
+		}
+		return nil
+	}
+
+	// The value follows the bundled short flags only if the last one takes a value, e.g. "-vi <path>"
+	for j, name := range word[1:] {
+		for _, fl := range flags {
+			if fl.Short != string(name) || fl.Placeholder == "" {
+				continue
+			}
+			if j == len(word)-2 {
+				return &fl
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// CompleteCommand is the hidden command called by the completion scripts, it prints the candidates of the
+// last argument one per line, followed by the directive line ":<directive>".
+type CompleteCommand struct {
+	flagset *alflag.FlagSet
+
+	root *Group
+}
+
+func NewCompleteCommand(root *Group) *CompleteCommand {
+	// The arguments are the words to complete, none of them is a flag of this command
+	flagset := alflag.NewFlagSet(CompleteCommandName)
+	flagset.SetInterspersed(false)
+
+	return &CompleteCommand{
+		flagset: flagset,
+
+		root: root,
+	}
+}
+
+func (c *CompleteCommand) Name() string {
+	return CompleteCommandName
+}
+
+func (c *CompleteCommand) Description() string {
+	return "Print the completion candidates of the arguments"
+}
+
+func (c *CompleteCommand) FlagSet() *alflag.FlagSet {
+	return c.flagset
+}
+
+func (c *CompleteCommand) Hidden() bool {
+	return true
+}
+
+func (c *CompleteCommand) Usage() string {
+	return `
+Usage: emit __complete <argument>... <word>
+
+Print the completion candidates of the word one per line, followed by the directive line ":<directive>".
+`
+}
+
+func (c *CompleteCommand) Run(args []string) (int, error) {
+	candidates, directive := Complete(c.root, args)
+	for _, candidate := range candidates {
+		fmt.Fprintln(os.Stdout, candidate)
+	}
+	fmt.Fprintf(os.Stdout, ":%d\n", directive)
+	return ExitCodeSuccess, nil
+}
@@ -0,0 +1,89 @@
+package command
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// CompleteArgs completes the first positional argument with fixed candidates, and the others as files.
+func (f *fakeCommand) CompleteArgs(args []string, word string) ([]string, Directive) {
+	if len(args) != 0 {
+		return nil, DirectiveFiles
+	}
+	var candidates []string
+	for _, candidate := range []string{"user/repo", "-dash"} {
+		if strings.HasPrefix(candidate, word) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates, 0
+}
+
+func TestComplete(t *testing.T) {
+	type testcase struct {
+		args      []string
+		want      []string
+		directive Directive
+	}
+
+	tests := []testcase{
+		{nil, []string{"clone", "cache", "help"}, 0},
+		{[]string{""}, []string{"clone", "cache", "help"}, 0},
+		{[]string{"c"}, []string{"clone", "cache"}, 0},
+		{[]string{"_"}, nil, 0},
+		{[]string{"-"}, []string{"--help", "-h", "--verbose", "-v", "--config"}, 0},
+		{[]string{"--v"}, []string{"--verbose"}, 0},
+		{[]string{"-v", "cl"}, []string{"clone"}, 0},
+		{[]string{"--config", ""}, nil, DirectiveFiles},
+		{[]string{"--config", "emit.json", "cl"}, []string{"clone"}, 0},
+		{[]string{"unknown", ""}, nil, 0},
+
+		// Flags and values of a subcommand
+		{[]string{"clone", "-"}, []string{"--quiet", "-q", "-i", "--mode", "--output", "-o", "--verbose", "-v", "--config"}, 0},
+		{[]string{"clone", "--mo"}, []string{"--mode"}, 0},
+		{[]string{"clone", "--mode="}, []string{"--mode=git", "--mode=tar"}, 0},
+		{[]string{"clone", "--mode=t"}, []string{"--mode=tar"}, 0},
+		{[]string{"clone", "--unknown="}, nil, 0},
+		{[]string{"clone", "--mode", ""}, []string{"git", "tar"}, 0},
+		{[]string{"clone", "-i", ""}, nil, DirectiveFiles},
+		{[]string{"clone", "--output", ""}, nil, 0},
+		{[]string{"clone", "--config", ""}, nil, DirectiveFiles},
+
+		// Bundled short flags take the next word as the value only if the last one has a value
+		{[]string{"clone", "-qi", ""}, nil, DirectiveFiles},
+		{[]string{"clone", "-iq", ""}, []string{"user/repo", "-dash"}, 0},
+		{[]string{"clone", "-qv", ""}, []string{"user/repo", "-dash"}, 0},
+		{[]string{"clone", "-qi", "key", "u"}, []string{"user/repo"}, 0},
+
+		// Attached values and the terminator
+		{[]string{"clone", "--output=x.zip", ""}, []string{"user/repo", "-dash"}, 0},
+		{[]string{"clone", "--mode=tar", "user/repo", ""}, nil, DirectiveFiles},
+		{[]string{"clone", "--", "-"}, []string{"-dash"}, 0},
+		{[]string{"clone", "--", "-x", ""}, nil, DirectiveFiles},
+		{[]string{"clone", "user/repo", "-v", ""}, nil, DirectiveFiles},
+
+		// Nested groups
+		{[]string{"cache", ""}, []string{"list", "help"}, 0},
+		{[]string{"cache", "-"}, []string{"--help", "-h", "--verbose", "-v", "--config"}, 0},
+		{[]string{"-v", "cache", "list", "--"}, []string{"--all", "--config", "--verbose"}, 0},
+		{[]string{"cache", "list", "--config", ""}, nil, 0},
+
+		// The help command completes the path of a command
+		{[]string{"help", ""}, []string{"clone", "cache"}, 0},
+		{[]string{"help", "cache", "l"}, []string{"list"}, 0},
+		{[]string{"help", "clone", ""}, nil, 0},
+		{[]string{"cache", "help", ""}, []string{"list"}, 0},
+	}
+
+	root := newTestTree()
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.args), func(t *testing.T) {
+			got, directive := Complete(root, test.args)
+			if !reflect.DeepEqual(got, test.want) || directive != test.directive {
+				t.Errorf("Complete(%q) = %q, %d; want %q, %d", test.args, got, directive, test.want, test.directive)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package command
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sotvokun/emit/internal/pkg/alflag"
+)
+
+var (
+	//go:embed completion/emit.bash
+	completionBash string
+	//go:embed completion/emit.zsh
+	completionZsh string
+	//go:embed completion/emit.fish
+	completionFish string
+	//go:embed completion/emit.ps1
+	completionPowerShell string
+)
+
+// completionShells are the supported shells in the order of the help text.
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// completionScripts are the completion scripts of the shells, they call the hidden command "__complete" for
+// the candidates, see [CompleteCommand].
+var completionScripts = map[string]string{
+	"bash":       completionBash,
+	"zsh":        completionZsh,
+	"fish":       completionFish,
+	"powershell": completionPowerShell,
+}
+
+type CompletionCommand struct {
+	flagset *alflag.FlagSet
+
+	help *bool
+}
+
+func NewCompletionCommand() *CompletionCommand {
+	flagset := alflag.NewFlagSet("completion")
+	help := flagset.Bool("h, help", false, "Print this help message and exit")
+
+	return &CompletionCommand{
+		flagset: flagset,
+
+		help: help,
+	}
+}
+
+func (c *CompletionCommand) Name() string {
+	return "completion"
+}
+
+func (c *CompletionCommand) Description() string {
+	return "Generate the shell completion script"
+}
+
+func (c *CompletionCommand) FlagSet() *alflag.FlagSet {
+	return c.flagset
+}
+
+func (c *CompletionCommand) Usage() string {
+	return `
+Usage: emit completion [OPTIONS] <shell>
+
+Print the completion script of the shell. The commands, the options and their values are completed, as well
+as the references of a remote after "#", e.g. "emit degit user/repo#v<TAB>".
+
+` + c.flagset.FlagUsages() + `
+ARGUMENTS:
+` + alflag.FormatEntry("<shell>", "The shell of the script: bash, zsh, fish or powershell") + `
+INSTALLATION:
+    bash          emit completion bash > ~/.local/share/bash-completion/completions/emit
+    zsh           emit completion zsh > "${fpath[1]}/_emit"
+    fish          emit completion fish > ~/.config/fish/completions/emit.fish
+    powershell    emit completion powershell | Out-String | Invoke-Expression
+`
+}
+
+// CompleteArgs completes the names of the shells.
+func (c *CompletionCommand) CompleteArgs(args []string, word string) ([]string, Directive) {
+	if len(args) != 0 {
+		return nil, 0
+	}
+	var candidates []string
+	for _, shell := range completionShells {
+		if strings.HasPrefix(shell, word) {
+			candidates = append(candidates, shell)
+		}
+	}
+	return candidates, 0
+}
+
+func (c *CompletionCommand) Run(args []string) (int, error) {
+	if err := c.flagset.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	if *c.help {
+		fmt.Fprintln(os.Stdout, strings.TrimSpace(c.Usage()))
+		return ExitCodeSuccess, nil
+	}
+
+	if c.flagset.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "emit: missing shell")
+		return ExitCodeArgumentError, nil
+	}
+	script, ok := completionScripts[c.flagset.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "emit: unsupported shell '%s', expect bash, zsh, fish or powershell\n", c.flagset.Arg(0))
+		return ExitCodeArgumentError, nil
+	}
+	fmt.Fprint(os.Stdout, script)
+	return ExitCodeSuccess, nil
+}
@@ -0,0 +1,34 @@
+# bash completion for emit, generated by "emit completion bash"
+
+_emit() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+
+    # The words are split by the spaces only, so that "--mode=git" and "git@host:repo#ref" are single words
+    local line="${COMP_LINE:0:COMP_POINT}" words
+    read -r -a words <<< "$line"
+    [[ "$line" == *[[:space:]] ]] && words+=("")
+
+    local out
+    out=$(emit __complete "${words[@]:1}" 2>/dev/null) || return
+    local directive="${out##*:}"
+    out="${out%:*}"
+
+    # The candidates are full words, bash replaces only the part after the last word break character
+    local word="${words[${#words[@]}-1]}"
+    local skip=$(( ${#word} - ${#cur} ))
+
+    COMPREPLY=()
+    local candidate
+    while IFS= read -r candidate; do
+        [[ -n "$candidate" ]] && COMPREPLY+=("${candidate:skip}")
+    done <<< "$out"
+
+    if (( directive & 1 )); then
+        compopt -o filenames
+        while IFS= read -r candidate; do
+            COMPREPLY+=("$candidate")
+        done < <(compgen -f -- "$cur")
+    fi
+}
+
+complete -F _emit emit
@@ -0,0 +1,15 @@
+# fish completion for emit, generated by "emit completion fish"
+
+function __emit_complete
+    set -l args (commandline -opc)[2..-1] (commandline -ct)
+    set -l out (emit __complete $args 2>/dev/null)
+    set -l directive (string replace ':' '' -- $out[-1])
+    set -e out[-1]
+
+    if test (math "$directive % 2") -eq 1
+        __fish_complete_path (commandline -ct)
+    end
+    printf '%s\n' $out
+end
+
+complete -c emit -f -a '(__emit_complete)'
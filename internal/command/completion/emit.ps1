@@ -0,0 +1,33 @@
+# PowerShell completion for emit, generated by "emit completion powershell"
+
+Register-ArgumentCompleter -Native -CommandName emit -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    # The words before the cursor, an empty word is completed after a space
+    $line = "$commandAst"
+    if ($line.Length -gt $cursorPosition) {
+        $line = $line.Substring(0, $cursorPosition)
+    }
+    $program, $arguments = $line.Split(" ", 2)
+    $request = "$program __complete $arguments"
+    if ($line.EndsWith(" ")) {
+        $request += ' ""'
+    }
+
+    $out = @(Invoke-Expression -Command $request 2>$null)
+    if ($out.Count -eq 0) {
+        return
+    }
+    $directive = [int]($out[-1].TrimStart(':'))
+    $candidates = @($out | Select-Object -SkipLast 1)
+
+    if (($directive -band 1) -and $candidates.Count -eq 0) {
+        # No result falls back to the completion of the file paths
+        return
+    }
+    foreach ($candidate in $candidates) {
+        if ($candidate -like "$wordToComplete*") {
+            [System.Management.Automation.CompletionResult]::new($candidate, $candidate, 'ParameterValue', $candidate)
+        }
+    }
+}
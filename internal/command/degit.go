@@ -2,55 +2,121 @@ package command
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"regexp"
 	"strings"
 
 	"github.com/sotvokun/emit/internal/pkg/alflag"
-	"github.com/sotvokun/emit/internal/service/degit"
-)
-
-var (
-	DegitCommandRemoteGitHubShortcutRegexp = regexp.MustCompile(`^([a-zA-Z0-9\_\.\-]+)\/([a-zA-Z0-9\_\.\-]+)$`)
+	"github.com/sotvokun/emit/internal/service/progress"
+	"github.com/sotvokun/emit/pkg/degit"
 )
 
 type DegitCommand struct {
 	flagset *alflag.FlagSet
 
-	help    *bool
-	dryRun  *bool
-	verbose *bool
+	help     *bool
+	dryRun   *bool
+	verbose  *bool
+	quiet    *bool
+	progress *string
+
+	submodules     *string
+	submodulePaths *[]string
+	submoduleURLs  *map[string]string
+	submoduleAuths *map[string]string
+	lfs            *string
+	mode           *string
+
+	includeUncommitted *bool
+
+	include *[]string
+	exclude *[]string
+
+	output    *string
+	tarOutput *string
+
+	lock     *bool
+	lockFile *string
 
-	identity  *string
-	username  *string
-	secrets   *string
-	noSecrets *bool
+	remote *remoteOptions
 }
 
 func NewDegitCommand() *DegitCommand {
 	flagset := alflag.NewFlagSet("degit")
-	help := flagset.Bool("h, help", false)
-
-	identity := flagset.String("i", "")
-	username := flagset.String("l", "")
-	secrets := flagset.String("p", "")
-	noSecrets := flagset.Bool("no-secrets", false)
-
-	dryRun := flagset.Bool("dry-run", false)
-	verbose := flagset.Bool("v, verbose", false)
+	help := flagset.Bool("h, help", false, "Print this help message and exit")
+	flagset.SetEnvPrefix(envPrefix(flagset.Name()))
+	dryRun := flagset.Bool("dry-run", false, "Dry run the command, will not clone the repository")
+	verbose := flagset.Bool("v, verbose", false, "Enable verbose output")
+	flagset.BindEnv("verbose", EnvPrefix+"_VERBOSE")
+	quiet := flagset.Bool("q, quiet", false, "Disable the progress output")
+	progress := flagset.String("progress", "auto", "Progress output `mode`: auto, bar, plain, json, none\n"+
+		"The \"auto\" mode uses a bar on terminals and plain lines otherwise")
+
+	flagset.SetGroup("SOURCE OPTIONS")
+	mode := flagset.String("mode", "git", "How to fetch a remote repository, the `mode` is git or tar\n"+
+		"The \"tar\" mode downloads the archive of the commit from GitHub, GitLab or Bitbucket, "+
+		"which is faster but does not include the submodules")
+	submodules := flagset.String("submodules", "shallow", "How to clone the submodules, the `mode` is shallow, full or none")
+	submodulePaths := flagset.StringSlice("submodule", nil, "Clone only the submodule at the `path`, can be repeated")
+	submoduleURLs := flagset.StringMap("submodule-url", nil,
+		"Rewrite the submodule URLs starting with the prefix, given as `<prefix>=<replacement>`, can be repeated")
+	submoduleAuths := flagset.StringMap("submodule-auth", nil,
+		"Basic authentication for the submodule at the path, given as `<path>=<username>[:<password>]`, can be repeated")
+	lfs := flagset.String("lfs", "fetch", "How to handle the Git LFS files, the `mode` is fetch, pointer or skip\n"+
+		"The \"fetch\" mode downloads the objects with the HTTP authentication of the clone, "+
+		"the SSH remotes cannot fetch the objects of private repositories")
+	includeUncommitted := flagset.Bool("include-uncommitted", false,
+		"Copy the current state of a local working tree, including the uncommitted changes")
+	include := flagset.StringSlice("include", nil, "Copy only the files matching the gitignore-style `pattern`, can be repeated")
+	exclude := flagset.StringSlice("exclude", nil, "Do not copy the files matching the gitignore-style `pattern`, can be repeated")
+
+	flagset.SetGroup("OUTPUT OPTIONS")
+	output := flagset.String("o, output", "", "Write an archive instead of a directory, the format of the `file` is chosen "+
+		"by the extension: .tar, .tar.gz, .tgz, .tar.zst, .zip; \"-\" writes a tar stream to stdout\n"+
+		"The archives are reproducible: sorted entries, fixed timestamps and modes")
+	tarOutput := flagset.String("tar", "", "Write a tar archive into the `file` instead of a directory, \"-\" writes to stdout")
+	lock := flagset.Bool("lock", false, "Write a lock file recording the remote, the ref, the commit and the file hashes "+
+		"into the destination, at .emit/lock.json by default")
+	lockFile := flagset.String("lock-file", "", "The `path` of the lock file in the destination, implies \"--lock\"")
+
+	remote := newRemoteOptions(flagset)
+
+	flagset.SetEnum("progress", "auto", "bar", "plain", "json", "none")
+	flagset.SetEnum("mode", "git", "tar")
+	flagset.SetEnum("submodules", "shallow", "full", "none")
+	flagset.SetEnum("lfs", "fetch", "pointer", "skip")
+	flagset.MarkExclusive("output", "tar")
+	flagset.MarkFilename("output", "tar")
 
 	return &DegitCommand{
 		flagset: flagset,
 
-		help:    help,
-		dryRun:  dryRun,
-		verbose: verbose,
+		help:     help,
+		dryRun:   dryRun,
+		verbose:  verbose,
+		quiet:    quiet,
+		progress: progress,
+
+		submodules:     submodules,
+		submodulePaths: submodulePaths,
+		submoduleURLs:  submoduleURLs,
+		submoduleAuths: submoduleAuths,
+		lfs:            lfs,
+		mode:           mode,
+
+		includeUncommitted: includeUncommitted,
+
+		include: include,
+		exclude: exclude,
+
+		output:    output,
+		tarOutput: tarOutput,
 
-		identity:  identity,
-		username:  username,
-		secrets:   secrets,
-		noSecrets: noSecrets,
+		lock:     lock,
+		lockFile: lockFile,
+
+		remote: remote,
 	}
 }
 
@@ -58,42 +124,56 @@ func (d *DegitCommand) Name() string {
 	return "degit"
 }
 
+func (d *DegitCommand) Description() string {
+	return "Clone a repository from a remote URL"
+}
+
+func (d *DegitCommand) FlagSet() *alflag.FlagSet {
+	return d.flagset
+}
+
 func (d *DegitCommand) Usage() string {
 	return `
 Usage: emit degit [OPTIONS] <remote>[#<ref>] [<destination>]
+       emit degit [OPTIONS] (-o <file> | --tar <file>) <remote>[#<ref>]
 
-OPTIONS:
-    -i <identity_file_path>    Path to the identity file to use for the SSH authentication
-    -l <username>              Username to use for the authentication
-    -p <secrets>               Password for the basic authentication, or the passphrase for the public key authentication
-    --no-secrets               Skip the interactive secrets prompt for the authentication
-    --dry-run                  Dry run the command, will not clone the repository
-    -v, --verbose              Enable verbose output
-    -h, --help                 Print this help message and exit
-
+` + d.flagset.FlagUsages() + `
 ARGUMENTS:
-    <remote>                   The remote URL of a Git repository
-    <ref>                      (OPTIONAL) The reference to clone (support: branch, tag, commit hash)
-                               Use the HEAD reference if not specified
-    <destination>              (OPTIONAL) The destination directory to clone the repository into
-                               Use the current directory if not specified
-
+` + alflag.FormatEntry("<remote>", "The remote URL of a Git repository, or a local path to a working tree, "+
+		"a bare repository, or a bundle file, starting with ./, ../ or / (e.g. ../template, file:///srv/git/t.git), "+
+		"or a .tar.gz, .tar.zst or .zip archive as a local path or an HTTP URL\n"+
+		"The top-level directory of an archive is stripped if it contains all the files") +
+		alflag.FormatEntry("<ref>", "(OPTIONAL) The reference to clone, matched in the order of:") +
+		`                                   1. full reference name, e.g. refs/heads/main, refs/pull/123/head
+                                   2. branch or tag name; a name of both a branch and a tag is
+                                      ambiguous, use the full reference name instead
+                                   3. commit hash, or a prefix of at least 4 characters, which a branch
+                                      or tag points to; other commits cannot be cloned
+                               or a selector choosing the highest matching tag:
+                                   latest               The highest release version tag
+                                   latest-prerelease    The highest version tag including prereleases
+                                   <range>              A semantic version range, e.g. ^2.1, ~1.2.3, >=1 <3
+                                   <pattern>            A glob pattern, e.g. release-*
+                               Use the HEAD reference if not specified, archives have no references
+` + alflag.FormatEntry("<destination>", "(OPTIONAL) The destination directory to clone the repository into\n"+
+		"Use the current directory if not specified") + `
 AUTHENTICATION:
     Basic Authentication:
         Provide "-l" option with the username, will enable basic authentication.
-    	The interactive password prompt will be shown when the "-p" or "--no-secrets" option is not provided.
+        The interactive password prompt will be shown when the "-p" or "--no-secrets" option is not
+        provided.
 
     Public Key Authentication:
         Provide "-i" option with the path to the identity file, will enable public key authentication.
-    	By default, the username is "git", and the interactive passphrase prompt will not be shown.
-    	Once "-l" option is provided, the interactive passphrase prompt will be shown by default,
-    	except the passphrase is provided with "-p" option or "--no-secrets" option is provided.
+        By default, the username is "git", and the interactive passphrase prompt will not be shown.
+        Once "-l" option is provided, the interactive passphrase prompt will be shown by default,
+        except the passphrase is provided with "-p" option or "--no-secrets" option is provided.
 `
 }
 
 func (d *DegitCommand) Run(args []string) (int, error) {
 	if err := d.flagset.Parse(args); err != nil {
-		return ExitCodeInternalError, err
+		return parseError(err)
 	}
 
 	if *d.help {
@@ -109,65 +189,241 @@ func (d *DegitCommand) Run(args []string) (int, error) {
 	arg := d.flagset.Arg(0)
 	remote, ref := d.parseArgument(arg)
 
-	degitService, err := d.createService(remote)
+	output, format, err := d.outputFormat()
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "emit: failed to create degit service")
-		return ExitCodeInternalError, err
+		fmt.Fprintf(os.Stderr, "emit: %v\n", err)
+		return ExitCodeArgumentError, nil
+	}
+
+	if *d.includeUncommitted && len(ref) != 0 {
+		fmt.Fprintln(os.Stderr, "emit: --include-uncommitted cannot be used with a ref")
+		return ExitCodeArgumentError, nil
+	}
+
+	options, err := d.options(remote, ref)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "emit: %v\n", err)
+		return ExitCodeArgumentError, nil
 	}
 
 	if *d.verbose {
-		logger := log.New(os.Stdout, "", log.LstdFlags)
-		degitService.SetLogger(logger)
+		logOutput := os.Stdout
+		if output == "-" {
+			logOutput = os.Stderr
+		}
+		options.Logger = log.New(logOutput, "", log.LstdFlags)
+		logEnvSources(options.Logger, d.flagset)
 	}
 
-	destDir := "."
-	if d.flagset.NArg() >= 2 {
-		destDir = d.flagset.Arg(1)
+	options.Auth = d.remote.auth()
+
+	closeOutput := func(err error) error { return err }
+	if len(output) != 0 {
+		options.Destination, closeOutput, err = d.createArchive(output, format)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "emit: failed to create the output archive")
+			return ExitCodeInternalError, err
+		}
+	} else {
+		destDir := "."
+		if d.flagset.NArg() >= 2 {
+			destDir = d.flagset.Arg(1)
+		}
+		options.Destination = degit.NewOSDestination(destDir)
+	}
+
+	dg, err := degit.New(options)
+	if err != nil {
+		closeOutput(err)
+		fmt.Fprintln(os.Stderr, "emit: failed to create degit service")
+		return ExitCodeInternalError, err
 	}
 
-	if err := degitService.Clone(ref, destDir, *d.dryRun); err != nil {
-		fmt.Fprintln(os.Stderr, "emit: failed to clone the repository")
+	ctx, cancel := d.remote.context()
+	defer cancel()
+
+	resolution, err := dg.Resolve(ctx)
+	if err != nil {
+		closeOutput(err)
+		if !d.remote.printContextError(err) {
+			fmt.Fprintln(os.Stderr, "emit: failed to resolve the reference")
+		}
 		return ExitCodeInternalError, err
 	}
+	if resolution.Selected {
+		fmt.Fprintf(os.Stderr, "emit: selected tag %s (commit %s) for '%s'\n",
+			resolution.Reference.Name().Short(), resolution.Commit, ref)
+	}
+
+	if err := closeOutput(dg.CloneResolution(ctx, resolution)); err != nil {
+		if !d.remote.printContextError(err) {
+			fmt.Fprintln(os.Stderr, "emit: failed to clone the repository")
+		}
+		return ExitCodeInternalError, err
+	}
+	for _, path := range dg.SkippedSubmodules() {
+		fmt.Fprintf(os.Stderr, "emit: skipped submodule %s\n", path)
+	}
 
 	return ExitCodeSuccess, nil
 }
 
-func (d *DegitCommand) createService(remote string) (*degit.DegitService, error) {
-	var degitService *degit.DegitService
-	if len(*d.identity) != 0 {
-		if len(*d.username) == 0 {
-			*d.username = "git"
+// options returns the degit options selected by the command line options, except the authentication,
+// the logger and the destination.
+func (d *DegitCommand) options(remote string, ref string) (degit.Options, error) {
+	options := degit.Options{
+		Remote:             remote,
+		Ref:                ref,
+		Include:            *d.include,
+		Exclude:            *d.exclude,
+		DryRun:             *d.dryRun,
+		SubmodulePaths:     *d.submodulePaths,
+		IncludeUncommitted: *d.includeUncommitted,
+		Retries:            *d.remote.retries,
+	}
+	if *d.lock || len(*d.lockFile) != 0 {
+		options.LockFile = degit.DefaultLockFile
+		if len(*d.lockFile) != 0 {
+			options.LockFile = *d.lockFile
 		}
-		passphrase := ""
-		if len(*d.secrets) != 0 {
-			passphrase = *d.secrets
+	}
+
+	var err error
+	if options.Mode, err = degit.ParseMode(*d.mode); err != nil {
+		return options, err
+	}
+	if options.LFS, err = degit.ParseLFSMode(*d.lfs); err != nil {
+		return options, err
+	}
+	if options.Submodules, err = degit.ParseSubmoduleMode(*d.submodules); err != nil {
+		return options, err
+	}
+
+	if len(*d.submoduleURLs) != 0 {
+		options.SubmoduleURLRewrites = *d.submoduleURLs
+	}
+
+	for path, credentials := range *d.submoduleAuths {
+		if len(credentials) == 0 {
+			return options, fmt.Errorf("invalid submodule authentication '%s=', expect <path>=<username>[:<password>]", path)
 		}
-		if *d.username == "git" || (len(passphrase) == 0 && !*d.noSecrets) {
-			fmt.Printf("Passphrase: ")
-			fmt.Scanln(&passphrase)
+		username, password, _ := strings.Cut(credentials, ":")
+		if options.SubmoduleAuth == nil {
+			options.SubmoduleAuth = make(map[string]degit.Auth)
 		}
+		options.SubmoduleAuth[path] = degit.Auth{Username: username, Password: password}
+	}
+
+	reporter, err := d.createProgress()
+	if err != nil {
+		return options, err
+	}
+	if reporter != nil {
+		options.Progress = reporter
+	}
+	return options, nil
+}
+
+// outputFormat returns the archive file and its format selected by the "--output" and "--tar" options,
+// an empty file is returned if the files are copied into a directory.
+func (d *DegitCommand) outputFormat() (string, string, error) {
+	if len(*d.output) == 0 && len(*d.tarOutput) == 0 {
+		return "", "", nil
+	}
+	if d.flagset.NArg() >= 2 {
+		return "", "", fmt.Errorf("the destination cannot be used with an output archive")
+	}
+
+	if len(*d.tarOutput) != 0 {
+		return *d.tarOutput, degit.ArchiveTar, nil
+	}
+	if *d.output == "-" {
+		return *d.output, degit.ArchiveTar, nil
+	}
+	format := degit.ArchiveFormat(*d.output)
+	if len(format) == 0 {
+		return "", "", fmt.Errorf("unsupported output format of '%s', expect .tar, .tar.gz, .tgz, .tar.zst or .zip", *d.output)
+	}
+	return *d.output, format, nil
+}
 
+// createArchive creates the destination writing an archive to the file, or to stdout if the file is "-".
+// The file must not exist. The returned function closes the file, and removes it if the given error is not nil.
+func (d *DegitCommand) createArchive(file string, format string) (degit.Destination, func(error) error, error) {
+	var w io.Writer = os.Stdout
+	var f *os.File
+	if *d.dryRun {
+		w = io.Discard
+	} else if file != "-" {
 		var err error
-		degitService, err = degit.NewDegitServiceWithPublicKey(remote, *d.identity, *d.username, passphrase)
+		f, err = os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		w = f
+	}
+
+	dest, err := degit.NewArchiveDestination(w, format)
+	if err != nil {
+		if f != nil {
+			f.Close()
+			os.Remove(file)
+		}
+		return nil, nil, err
+	}
+
+	closeOutput := func(err error) error {
+		if f == nil {
+			return err
 		}
-	} else if len(*d.username) != 0 {
-		password := ""
-		if len(*d.secrets) != 0 {
-			password = *d.secrets
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
 		}
-		if len(password) == 0 && !*d.noSecrets {
-			fmt.Printf("Password: ")
-			fmt.Scanln(&password)
+		if err != nil {
+			os.Remove(file)
 		}
-		degitService = degit.NewDegitServiceWithBasicAuth(remote, *d.username, password)
-	} else {
-		degitService = degit.NewDegitService(remote)
+		return err
 	}
+	return dest, closeOutput, nil
+}
+
+// createProgress returns the progress reporter selected by the "--progress" and "--quiet" options,
+// `nil` will be returned if the progress output is disabled.
+func (d *DegitCommand) createProgress() (progress.Reporter, error) {
+	mode := *d.progress
+	if *d.quiet {
+		mode = "none"
+	}
+	if mode == "auto" {
+		mode = "plain"
+		if progress.IsTerminal(os.Stderr) {
+			mode = "bar"
+		}
+	}
+
+	switch mode {
+	case "bar":
+		return progress.NewBar(os.Stderr), nil
+	case "plain":
+		return progress.NewPlain(os.Stderr), nil
+	case "json":
+		return progress.NewJSON(os.Stderr), nil
+	case "none":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("invalid progress mode '%s'", *d.progress)
+}
 
-	return degitService, nil
+// CompleteArgs completes the references of the remote after "#", and the local paths of the remote and the
+// destination.
+func (d *DegitCommand) CompleteArgs(args []string, word string) ([]string, Directive) {
+	if len(args) == 0 && strings.Contains(word, "#") {
+		return completeRefs(word), 0
+	}
+	if len(args) <= 1 {
+		return nil, DirectiveFiles
+	}
+	return nil, 0
 }
 
 func (d *DegitCommand) parseArgument(arg string) (string, string) {
@@ -179,9 +435,5 @@ func (d *DegitCommand) parseArgument(arg string) (string, string) {
 		ref = parts[1]
 	}
 
-	if DegitCommandRemoteGitHubShortcutRegexp.MatchString(remote) {
-		remote = fmt.Sprintf("https://github.com/%s.git", remote)
-	}
-
-	return remote, ref
+	return expandRemote(remote), ref
 }
@@ -0,0 +1,201 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/sotvokun/emit/internal/pkg/alflag"
+	"github.com/sotvokun/emit/internal/service/update"
+	"github.com/sotvokun/emit/pkg/degit"
+)
+
+type DiffCommand struct {
+	flagset *alflag.FlagSet
+
+	help       *bool
+	verbose    *bool
+	ref        *string
+	lockFile   *string
+	stat       *bool
+	nameStatus *bool
+	unified    *int
+
+	remote *remoteOptions
+}
+
+func NewDiffCommand() *DiffCommand {
+	flagset := alflag.NewFlagSet("diff")
+	help := flagset.Bool("h, help", false, "Print this help message and exit")
+	flagset.SetEnvPrefix(envPrefix(flagset.Name()))
+	verbose := flagset.Bool("v, verbose", false, "Enable verbose output")
+	flagset.BindEnv("verbose", EnvPrefix+"_VERBOSE")
+	ref := flagset.String("ref", "", "Compare with the `ref` instead of the commit recorded in the lock file, "+
+		"e.g. a branch, a tag or a selector like \"latest\" or \"^2\"")
+	lockFile := flagset.String("lock-file", degit.DefaultLockFile, "The `path` of the lock file in the destination")
+	stat := flagset.Bool("stat", false, "Print the number of changed lines of each file instead of the diff")
+	nameStatus := flagset.Bool("name-status", false, "Print the status and the path of each changed file instead of the diff")
+	unified := flagset.Int("U, unified", 3, "The number of context `lines` of the diff")
+
+	remote := newRemoteOptions(flagset)
+
+	flagset.MarkExclusive("stat", "name-status")
+	flagset.AddValidator("unified", func() error {
+		if *unified < 0 {
+			return fmt.Errorf("must not be negative")
+		}
+		return nil
+	})
+
+	return &DiffCommand{
+		flagset: flagset,
+
+		help:       help,
+		verbose:    verbose,
+		ref:        ref,
+		lockFile:   lockFile,
+		stat:       stat,
+		nameStatus: nameStatus,
+		unified:    unified,
+
+		remote: remote,
+	}
+}
+
+func (d *DiffCommand) Name() string {
+	return "diff"
+}
+
+func (d *DiffCommand) Description() string {
+	return "Show how a project created by degit has drifted from its template"
+}
+
+func (d *DiffCommand) FlagSet() *alflag.FlagSet {
+	return d.flagset
+}
+
+func (d *DiffCommand) Usage() string {
+	return `
+Usage: emit diff [OPTIONS] [<destination>]
+
+Show how the destination has drifted from the template recorded in the lock file.
+The destination must have been created by "emit degit --lock".
+
+` + d.flagset.FlagUsages() + `
+ARGUMENTS:
+` + alflag.FormatEntry("<destination>", "(OPTIONAL) The directory created from the template\n"+
+		"Use the current directory if not specified") + `
+STATUS:
+    A    The file is in the destination only, e.g. it was deleted from the template since the lock
+    M    The file was changed
+    D    The file is in the template only
+
+    The files of the template and the files recorded in the lock file are compared, with the include
+    and exclude filters recorded in the lock file. The other files of the destination are not shown.
+`
+}
+
+func (d *DiffCommand) Run(args []string) (int, error) {
+	if err := d.flagset.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	if *d.help {
+		fmt.Fprintln(os.Stdout, strings.TrimSpace(d.Usage()))
+		return ExitCodeSuccess, nil
+	}
+
+	destDir := "."
+	if d.flagset.NArg() >= 1 {
+		destDir = d.flagset.Arg(0)
+	}
+
+	if err := degit.ValidateLockFile(*d.lockFile); err != nil {
+		fmt.Fprintf(os.Stderr, "emit: %v\n", err)
+		return ExitCodeArgumentError, nil
+	}
+	lock, err := degit.ReadLock(filepath.Join(destDir, filepath.FromSlash(*d.lockFile)))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "emit: failed to read the lock file")
+		return ExitCodeInternalError, err
+	}
+
+	options, err := lockOptions(lock, d.remote)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "emit: invalid lock file")
+		return ExitCodeInternalError, err
+	}
+	if len(*d.ref) != 0 {
+		options.Ref = *d.ref
+	}
+	if *d.verbose {
+		options.Logger = log.New(os.Stderr, "", log.LstdFlags)
+		logEnvSources(options.Logger, d.flagset)
+	}
+
+	ctx, cancel := d.remote.context()
+	defer cancel()
+
+	// The recorded commit is compared unless another ref is requested, archive sources have no commit
+	resolution := &degit.Resolution{Requested: lock.Ref, Commit: plumbing.NewHash(lock.Commit)}
+	if len(*d.ref) != 0 || len(lock.Commit) == 0 {
+		dg, err := degit.New(options)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "emit: failed to create degit service")
+			return ExitCodeInternalError, err
+		}
+		resolution, err = dg.Resolve(ctx)
+		if err != nil {
+			if !d.remote.printContextError(err) {
+				fmt.Fprintln(os.Stderr, "emit: failed to resolve the reference")
+			}
+			return ExitCodeInternalError, err
+		}
+	}
+
+	template, err := fetchTemplate(ctx, options, resolution)
+	if err != nil {
+		if !d.remote.printContextError(err) {
+			fmt.Fprintln(os.Stderr, "emit: failed to fetch the template")
+		}
+		return ExitCodeInternalError, err
+	}
+
+	var paths []string
+	for path := range lock.Files {
+		paths = append(paths, path)
+	}
+	diffs, err := update.NewUpdateService(osfs.New(destDir)).Diff(template, paths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "emit: failed to compare the files")
+		return ExitCodeInternalError, err
+	}
+
+	switch {
+	case *d.nameStatus:
+		for _, diff := range diffs {
+			fmt.Fprintf(os.Stdout, "%c\t%s\n", diff.Status, diff.Path)
+		}
+	case *d.stat:
+		if len(diffs) != 0 {
+			fmt.Fprint(os.Stdout, update.Stat(diffs))
+		}
+	default:
+		for _, diff := range diffs {
+			fmt.Fprint(os.Stdout, diff.Unified(*d.unified))
+		}
+	}
+	return ExitCodeSuccess, nil
+}
+
+// CompleteArgs completes the destination.
+func (d *DiffCommand) CompleteArgs(args []string, word string) ([]string, Directive) {
+	if len(args) == 0 {
+		return nil, DirectiveFiles
+	}
+	return nil, 0
+}
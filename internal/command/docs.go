@@ -0,0 +1,353 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sotvokun/emit/internal/pkg/alflag"
+)
+
+// exitCodes are the exit codes of the commands with their descriptions in the documentation.
+var exitCodes = []exitCode{
+	{ExitCodeSuccess, "The command succeeded", ""},
+	{ExitCodeArgumentError, "The arguments are invalid", ""},
+	{ExitCodeInternalError, "The command failed", ""},
+	{ExitCodeConflict, "Some files have conflicts to resolve", "update"},
+}
+
+// exitCode is an exit code in the documentation.
+type exitCode struct {
+	code        int
+	description string
+	// command is the name of the only command returning the code, every command returns it if empty
+	command string
+}
+
+// docHeadingRegexp matches the heading of a section of the usage, e.g. "ARGUMENTS:".
+var docHeadingRegexp = regexp.MustCompile(`^[A-Z][A-Z ]*:$`)
+
+// docSection is a section of the usage which is not generated from the flags, e.g. "ARGUMENTS".
+type docSection struct {
+	title string
+	lines []string
+}
+
+// commandDoc is the documentation of a command, the flags are taken from their metadata and the rest is parsed
+// from the usage of the command, so that the documentation is the same as the help message.
+type commandDoc struct {
+	path        string
+	description string
+	synopsis    []string
+	intro       []string
+	flags       []alflag.Flag
+	inherited   []alflag.Flag
+	sections    []docSection
+	commands    []Command
+	exitCodes   []exitCode
+}
+
+// newCommandDoc returns the documentation of the command at the path, e.g. "emit degit".
+func newCommandDoc(path string, command Command) commandDoc {
+	doc := commandDoc{
+		path:        path,
+		description: command.Description(),
+		flags:       command.FlagSet().Flags(),
+		inherited:   command.FlagSet().InheritedFlags(),
+	}
+	for _, exit := range exitCodes {
+		if exit.command == "" || exit.command == command.Name() {
+			doc.exitCodes = append(doc.exitCodes, exit)
+		}
+	}
+	if group, ok := command.(*Group); ok {
+		for _, sub := range group.Commands() {
+			if !isHidden(sub) {
+				doc.commands = append(doc.commands, sub)
+			}
+		}
+	}
+
+	// The sections of the flags are generated from the metadata
+	generated := map[string]bool{"OPTIONS": true, "GLOBAL OPTIONS": true, "COMMANDS": true}
+	for _, fl := range doc.flags {
+		generated[fl.Group] = true
+	}
+
+	lines := strings.Split(strings.TrimSpace(command.Usage()), "\n")
+	i := 0
+	for ; i < len(lines) && lines[i] != ""; i++ {
+		line := strings.TrimPrefix(lines[i], "Usage:")
+		doc.synopsis = append(doc.synopsis, strings.TrimSpace(line))
+	}
+	for ; i < len(lines) && !docHeadingRegexp.MatchString(lines[i]); i++ {
+		doc.intro = append(doc.intro, lines[i])
+	}
+	for ; i < len(lines); i++ {
+		if docHeadingRegexp.MatchString(lines[i]) {
+			doc.sections = append(doc.sections, docSection{title: strings.TrimSuffix(lines[i], ":")})
+			continue
+		}
+		section := &doc.sections[len(doc.sections)-1]
+		section.lines = append(section.lines, strings.TrimPrefix(lines[i], strings.Repeat(" ", alflag.HelpIndent)))
+	}
+	doc.intro = trimLines(doc.intro)
+	// The usage of a group starts with its description, which is already the summary of the page
+	if len(doc.intro) == 1 && doc.intro[0] == doc.description {
+		doc.intro = nil
+	}
+	doc.sections = slices.DeleteFunc(doc.sections, func(section docSection) bool {
+		return generated[section.title]
+	})
+	for i := range doc.sections {
+		doc.sections[i].lines = trimLines(doc.sections[i].lines)
+	}
+	return doc
+}
+
+// name returns the name of the page, e.g. "emit-degit".
+func (doc commandDoc) name() string {
+	return strings.ReplaceAll(doc.path, " ", "-")
+}
+
+// groups returns the flags of each group in the order of registration, the inherited flags are the last group.
+func (doc commandDoc) groups() ([]string, map[string][]alflag.Flag) {
+	var titles []string
+	groups := make(map[string][]alflag.Flag)
+	for _, fl := range doc.flags {
+		title := fl.Group
+		if title == "" {
+			title = "OPTIONS"
+		}
+		if _, ok := groups[title]; !ok {
+			titles = append(titles, title)
+		}
+		groups[title] = append(groups[title], fl)
+	}
+	if len(doc.inherited) != 0 {
+		titles = append(titles, "GLOBAL OPTIONS")
+		groups["GLOBAL OPTIONS"] = doc.inherited
+	}
+	return titles, groups
+}
+
+// envFlags returns the flags bound to environment variables.
+func (doc commandDoc) envFlags() []alflag.Flag {
+	var flags []alflag.Flag
+	for _, fl := range append(slices.Clone(doc.flags), doc.inherited...) {
+		if len(fl.Env) != 0 {
+			flags = append(flags, fl)
+		}
+	}
+	return flags
+}
+
+// flagUsage returns the usage of the flag followed by the notes of its value as in the help message,
+// e.g. "(default: git; env: EMIT_DEGIT_MODE)". The lines of the usage are the paragraphs.
+func flagUsage(fl alflag.Flag) []string {
+	var notes []string
+	if fl.Required {
+		notes = append(notes, "required")
+	}
+	if len(fl.Enum) != 0 {
+		notes = append(notes, "choices: "+strings.Join(fl.Enum, ", "))
+	}
+	if fl.Default != "" {
+		notes = append(notes, "default: "+fl.Default)
+	}
+	if len(fl.Env) != 0 {
+		notes = append(notes, "env: "+strings.Join(fl.Env, ", "))
+	}
+
+	lines := strings.Split(fl.Usage, "\n")
+	if len(notes) != 0 {
+		lines[0] += " (" + strings.Join(notes, "; ") + ")"
+	}
+	return lines
+}
+
+// trimLines removes the leading and trailing empty lines.
+func trimLines(lines []string) []string {
+	for len(lines) != 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) != 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// titleCase returns the title with only its first letter in upper case, e.g. "Source options".
+func titleCase(title string) string {
+	if title == "" {
+		return title
+	}
+	return title[:1] + strings.ToLower(title[1:])
+}
+
+// Markdown renders the documentation as a Markdown page, the pages of the subcommands are linked as
+// "<name>.md".
+func (doc commandDoc) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n%s\n\n", doc.path, doc.description)
+
+	sb.WriteString("## Synopsis\n\n```\n" + strings.Join(doc.synopsis, "\n") + "\n```\n")
+	if len(doc.intro) != 0 {
+		sb.WriteString("\n" + markdownEscape(strings.Join(doc.intro, "\n")) + "\n")
+	}
+
+	if len(doc.commands) != 0 {
+		sb.WriteString("\n## Commands\n\n")
+		for _, command := range doc.commands {
+			name := doc.path + " " + command.Name()
+			fmt.Fprintf(&sb, "- [`%s`](%s.md): %s\n", name, strings.ReplaceAll(name, " ", "-"), command.Description())
+		}
+	}
+
+	titles, groups := doc.groups()
+	for _, title := range titles {
+		fmt.Fprintf(&sb, "\n## %s\n\n", titleCase(title))
+		for _, fl := range groups[title] {
+			fmt.Fprintf(&sb, "- `%s`: %s\n", fl.Name(), markdownEscape(strings.Join(flagUsage(fl), " ")))
+		}
+	}
+
+	for _, section := range doc.sections {
+		fmt.Fprintf(&sb, "\n## %s\n\n```\n%s\n```\n", titleCase(section.title), strings.Join(section.lines, "\n"))
+	}
+
+	if flags := doc.envFlags(); len(flags) != 0 {
+		sb.WriteString("\n## Environment\n\n")
+		for _, fl := range flags {
+			fmt.Fprintf(&sb, "- `%s`: the same as `%s`\n", strings.Join(fl.Env, "`, `"), fl.Name())
+		}
+	}
+
+	sb.WriteString("\n## Exit status\n\n")
+	for _, exit := range doc.exitCodes {
+		fmt.Fprintf(&sb, "- `%d`: %s\n", exit.code, exit.description)
+	}
+	return sb.String()
+}
+
+// Man renders the documentation as a man page in the section 1, the version is shown in the footer.
+func (doc commandDoc) Man(version string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, ".TH %s 1 \"\" %s \"Emit Manual\"\n", roffQuote(strings.ToUpper(doc.name())), roffQuote("emit "+version))
+	fmt.Fprintf(&sb, ".SH NAME\n%s \\- %s\n", roffEscape(doc.name()), roffEscape(doc.description))
+
+	sb.WriteString(".SH SYNOPSIS\n.nf\n")
+	for _, line := range doc.synopsis {
+		sb.WriteString(roffLine(line) + "\n")
+	}
+	sb.WriteString(".fi\n")
+	if len(doc.intro) != 0 {
+		sb.WriteString(".SH DESCRIPTION\n" + roffParagraphs(doc.intro))
+	}
+
+	if len(doc.commands) != 0 {
+		sb.WriteString(".SH COMMANDS\n")
+		for _, command := range doc.commands {
+			fmt.Fprintf(&sb, ".TP\n.B %s\n%s\n", roffEscape(command.Name()), roffLine(command.Description()))
+		}
+	}
+
+	titles, groups := doc.groups()
+	for _, title := range titles {
+		fmt.Fprintf(&sb, ".SH %s\n", roffQuote(title))
+		for _, fl := range groups[title] {
+			name := roffEscape(strings.TrimSuffix(fl.Name(), " "+fl.Placeholder))
+			if fl.Placeholder != "" {
+				name = "\\fB" + name + "\\fR \\fI" + roffEscape(fl.Placeholder) + "\\fR"
+			} else {
+				name = "\\fB" + name + "\\fR"
+			}
+			// Each line of the usage is a paragraph, see [alflag.FormatEntry]
+			var lines []string
+			for _, line := range flagUsage(fl) {
+				lines = append(lines, roffLine(line))
+			}
+			fmt.Fprintf(&sb, ".TP\n%s\n%s\n", name, strings.Join(lines, "\n.br\n"))
+		}
+	}
+
+	for _, section := range doc.sections {
+		fmt.Fprintf(&sb, ".SH %s\n.nf\n", roffQuote(section.title))
+		for _, line := range section.lines {
+			sb.WriteString(roffLine(line) + "\n")
+		}
+		sb.WriteString(".fi\n")
+	}
+
+	if flags := doc.envFlags(); len(flags) != 0 {
+		sb.WriteString(".SH ENVIRONMENT\n")
+		for _, fl := range flags {
+			fmt.Fprintf(&sb, ".TP\n.B %s\nThe same as %s\n", roffEscape(strings.Join(fl.Env, ", ")), roffEscape(fl.Name()))
+		}
+	}
+
+	sb.WriteString(".SH \"EXIT STATUS\"\n")
+	for _, exit := range doc.exitCodes {
+		fmt.Fprintf(&sb, ".TP\n.B %d\n%s\n", exit.code, roffLine(exit.description))
+	}
+
+	// The parent and the subcommands are referred to
+	var refs []string
+	if i := strings.LastIndex(doc.path, " "); i >= 0 {
+		refs = append(refs, strings.ReplaceAll(doc.path[:i], " ", "-"))
+	}
+	for _, command := range doc.commands {
+		refs = append(refs, doc.name()+"-"+command.Name())
+	}
+	for i, ref := range refs {
+		refs[i] = "\\fB" + roffEscape(ref) + "\\fR(1)"
+	}
+	if len(refs) != 0 {
+		sb.WriteString(".SH \"SEE ALSO\"\n" + strings.Join(refs, ",\n") + "\n")
+	}
+	return sb.String()
+}
+
+// markdownEscape escapes the angle brackets of the placeholders in the text, e.g. "<path>", which would be
+// taken as HTML tags.
+func markdownEscape(text string) string {
+	return strings.ReplaceAll(text, "<", `\<`)
+}
+
+// roffEscape escapes the backslashes and the dashes of the text.
+func roffEscape(text string) string {
+	return strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(text)
+}
+
+// roffLine escapes a line of text, a line starting with a control character is protected.
+func roffLine(line string) string {
+	line = roffEscape(line)
+	if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+		line = `\&` + line
+	}
+	return line
+}
+
+// roffQuote quotes an argument of a request.
+func roffQuote(text string) string {
+	return `"` + strings.ReplaceAll(roffEscape(text), `"`, `\(dq`) + `"`
+}
+
+// roffParagraphs renders the lines as filled text, the paragraphs are separated by the empty lines.
+func roffParagraphs(lines []string) string {
+	var sb strings.Builder
+	paragraph := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			paragraph = false
+			continue
+		}
+		if !paragraph && sb.Len() != 0 {
+			sb.WriteString(".PP\n")
+		}
+		paragraph = true
+		sb.WriteString(roffLine(strings.TrimSpace(line)) + "\n")
+	}
+	return sb.String()
+}
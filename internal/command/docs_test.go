@@ -0,0 +1,167 @@
+package command
+
+import (
+	"testing"
+)
+
+// newDocTree returns the command tree "emit {update}" used by the documentation tests.
+func newDocTree() *Group {
+	update := newFakeCommand("update")
+	update.usage = `Usage: emit update [OPTIONS] <dir>
+
+Update the files of <dir> from the template, the
+.emit.lock file records the template.
+
+OPTIONS:
+    --mode <mode>              The mode
+
+ARGUMENTS:
+    <dir>                      The directory, e.g. ./app or \\server\app
+`
+	update.flagset.String("mode", "merge", "The `mode`")
+	update.flagset.SetEnum("mode", "merge", "theirs")
+	update.flagset.BindEnv("mode", "EMIT_UPDATE_MODE")
+
+	root := NewGroup("emit", "The root", update)
+	root.FlagSet().Bool("v, verbose", false, "Verbose")
+	root.FlagSet().MarkPersistent("verbose")
+	return root
+}
+
+func TestCommandDoc_Markdown(t *testing.T) {
+	root := newDocTree()
+	doc := newCommandDoc("emit update", root.Lookup("update"))
+	want := "# emit update\n" + `
+The update command
+
+## Synopsis
+
+` + "```" + `
+emit update [OPTIONS] <dir>
+` + "```" + `
+
+Update the files of \<dir> from the template, the
+.emit.lock file records the template.
+
+## Options
+
+` + "- `--mode <mode>`" + `: The mode (choices: merge, theirs; default: merge; env: EMIT_UPDATE_MODE)
+
+## Global options
+
+` + "- `-v, --verbose`" + `: Verbose
+
+## Arguments
+
+` + "```" + `
+<dir>                      The directory, e.g. ./app or \\server\app
+` + "```" + `
+
+## Environment
+
+` + "- `EMIT_UPDATE_MODE`: the same as `--mode <mode>`" + `
+
+## Exit status
+
+` + "- `0`: The command succeeded\n- `1`: The arguments are invalid\n- `2`: The command failed\n" +
+		"- `3`: Some files have conflicts to resolve\n"
+	if got := doc.Markdown(); got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Only the update command returns the conflict exit code
+	want = "# emit\n" + `
+The root
+
+## Synopsis
+
+` + "```" + `
+emit [OPTIONS] <command> [<arguments>]
+` + "```" + `
+
+## Commands
+
+` + "- [`emit update`](emit-update.md): The update command" + `
+
+## Options
+
+` + "- `-h, --help`: Print this help message and exit\n- `-v, --verbose`: Verbose" + `
+
+## Exit status
+
+` + "- `0`: The command succeeded\n- `1`: The arguments are invalid\n- `2`: The command failed\n"
+	if got := newCommandDoc("emit", root).Markdown(); got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCommandDoc_Man(t *testing.T) {
+	doc := newCommandDoc("emit update", newDocTree().Lookup("update"))
+	want := `.TH "EMIT\-UPDATE" 1 "" "emit 1.0.0" "Emit Manual"
+.SH NAME
+emit\-update \- The update command
+.SH SYNOPSIS
+.nf
+emit update [OPTIONS] <dir>
+.fi
+.SH DESCRIPTION
+Update the files of <dir> from the template, the
+\&.emit.lock file records the template.
+.SH "OPTIONS"
+.TP
+\fB\-\-mode\fR \fI<mode>\fR
+The mode (choices: merge, theirs; default: merge; env: EMIT_UPDATE_MODE)
+.SH "GLOBAL OPTIONS"
+.TP
+\fB\-v, \-\-verbose\fR
+Verbose
+.SH "ARGUMENTS"
+.nf
+<dir>                      The directory, e.g. ./app or \e\eserver\eapp
+.fi
+.SH ENVIRONMENT
+.TP
+.B EMIT_UPDATE_MODE
+The same as \-\-mode <mode>
+.SH "EXIT STATUS"
+.TP
+.B 0
+The command succeeded
+.TP
+.B 1
+The arguments are invalid
+.TP
+.B 2
+The command failed
+.TP
+.B 3
+Some files have conflicts to resolve
+.SH "SEE ALSO"
+\fBemit\fR(1)
+`
+	if got := doc.Man("1.0.0"); got != want {
+		t.Errorf("Man() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRoffLine(t *testing.T) {
+	type testcase struct {
+		line string
+		want string
+	}
+
+	tests := []testcase{
+		{"plain text", "plain text"},
+		{"--mode", `\-\-mode`},
+		{`C:\path`, `C:\epath`},
+		{".emit.lock", `\&.emit.lock`},
+		{"'quoted'", `\&'quoted'`},
+		{"a .dot", "a .dot"},
+	}
+
+	for _, test := range tests {
+		if got := roffLine(test.line); got != test.want {
+			t.Errorf("roffLine(%q) = %q; want %q", test.line, got, test.want)
+		}
+	}
+}
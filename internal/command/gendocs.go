@@ -0,0 +1,115 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sotvokun/emit/internal/pkg/alflag"
+	"github.com/sotvokun/emit/internal/service/version"
+)
+
+// GenDocsCommand is the hidden command generating the reference of the commands as man pages or Markdown
+// pages, from the same metadata as the help messages.
+type GenDocsCommand struct {
+	flagset *alflag.FlagSet
+
+	help   *bool
+	format *string
+	out    *string
+
+	root *Group
+}
+
+func NewGenDocsCommand(root *Group) *GenDocsCommand {
+	flagset := alflag.NewFlagSet("gen-docs")
+	help := flagset.Bool("h, help", false, "Print this help message and exit")
+	format := flagset.String("format", "man", "The `format` of the pages: man or markdown")
+	out := flagset.String("out", "", "The `directory` to write the pages into, it is created if not exists")
+
+	flagset.SetEnum("format", "man", "markdown")
+	flagset.MarkFilename("out")
+
+	return &GenDocsCommand{
+		flagset: flagset,
+
+		help:   help,
+		format: format,
+		out:    out,
+
+		root: root,
+	}
+}
+
+func (g *GenDocsCommand) Name() string {
+	return "gen-docs"
+}
+
+func (g *GenDocsCommand) Description() string {
+	return "Generate the reference of the commands"
+}
+
+func (g *GenDocsCommand) FlagSet() *alflag.FlagSet {
+	return g.flagset
+}
+
+func (g *GenDocsCommand) Hidden() bool {
+	return true
+}
+
+func (g *GenDocsCommand) Usage() string {
+	return `
+Usage: emit gen-docs [OPTIONS] --out <directory>
+
+Generate a page for each command, e.g. "emit-degit.1" or "emit-degit.md", with its options, arguments,
+environment variables and exit codes.
+
+` + g.flagset.FlagUsages()
+}
+
+func (g *GenDocsCommand) Run(args []string) (int, error) {
+	if err := g.flagset.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	if *g.help {
+		fmt.Fprintln(os.Stdout, strings.TrimSpace(g.Usage()))
+		return ExitCodeSuccess, nil
+	}
+
+	if len(*g.out) == 0 {
+		fmt.Fprintln(os.Stderr, "emit: missing output directory, expect --out <directory>")
+		return ExitCodeArgumentError, nil
+	}
+	if err := os.MkdirAll(*g.out, 0755); err != nil {
+		fmt.Fprintln(os.Stderr, "emit: failed to create the output directory")
+		return ExitCodeInternalError, err
+	}
+	if err := g.write(g.root.Name(), g.root); err != nil {
+		fmt.Fprintln(os.Stderr, "emit: failed to write the pages")
+		return ExitCodeInternalError, err
+	}
+	return ExitCodeSuccess, nil
+}
+
+// write writes the page of the command at the path and the pages of its subcommands, the hidden commands are
+// skipped.
+func (g *GenDocsCommand) write(path string, command Command) error {
+	doc := newCommandDoc(path, command)
+
+	name, content := doc.name()+".1", doc.Man(version.NewVersionService().Version())
+	if *g.format == "markdown" {
+		name, content = doc.name()+".md", doc.Markdown()
+	}
+	if err := os.WriteFile(filepath.Join(*g.out, name), []byte(content), 0644); err != nil {
+		return err
+	}
+
+	for _, sub := range doc.commands {
+		if err := g.write(path+" "+sub.Name(), sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sotvokun/emit/internal/pkg/alflag"
+)
+
+// HelpCommandName is the name of the subcommand of every group printing the usage of a command,
+// e.g. "emit help cache list".
+const HelpCommandName = "help"
+
+// Group is a command dispatching to its subcommands, e.g. "emit cache list". The persistent flags of the
+// group are inherited by the subcommands, see [alflag.FlagSet.MarkPersistent].
+type Group struct {
+	flagset *alflag.FlagSet
+
+	help *bool
+
+	description string
+	parent      *Group
+	commands    []Command
+	action      func() (int, error)
+}
+
+// NewGroup returns a group of the subcommands, the usage of the group is generated from them.
+func NewGroup(name string, description string, commands ...Command) *Group {
+	flagset := alflag.NewFlagSet(name)
+	// The flags after the subcommand name belong to the subcommand
+	flagset.SetInterspersed(false)
+	help := flagset.Bool("h, help", false, "Print this help message and exit")
+
+	g := &Group{
+		flagset: flagset,
+
+		help: help,
+
+		description: description,
+	}
+	for _, command := range commands {
+		g.Add(command)
+	}
+	return g
+}
+
+// Add adds the subcommand, which inherits the persistent flags of the group.
+func (g *Group) Add(command Command) {
+	command.FlagSet().SetParent(g.flagset)
+	if group, ok := command.(*Group); ok {
+		group.parent = g
+	}
+	g.commands = append(g.commands, command)
+}
+
+// SetAction sets the function run when no subcommand is given, the usage is printed by default.
+func (g *Group) SetAction(action func() (int, error)) {
+	g.action = action
+}
+
+// Commands returns the subcommands in the order of addition.
+func (g *Group) Commands() []Command {
+	return g.commands
+}
+
+// Lookup returns the subcommand with the name, nil is returned if it does not exist.
+func (g *Group) Lookup(name string) Command {
+	for _, command := range g.commands {
+		if command.Name() == name {
+			return command
+		}
+	}
+	return nil
+}
+
+// Path returns the names of the group and its ancestors, e.g. "emit cache".
+func (g *Group) Path() string {
+	if g.parent == nil {
+		return g.Name()
+	}
+	return g.parent.Path() + " " + g.Name()
+}
+
+func (g *Group) Name() string {
+	return g.flagset.Name()
+}
+
+func (g *Group) Description() string {
+	return g.description
+}
+
+func (g *Group) FlagSet() *alflag.FlagSet {
+	return g.flagset
+}
+
+func (g *Group) Usage() string {
+	usage := `
+Usage: ` + g.Path() + ` [OPTIONS] <command> [<arguments>]
+
+`
+	if len(g.description) != 0 {
+		usage += g.description + "\n\n"
+	}
+	usage += g.flagset.FlagUsages() + `
+COMMANDS:
+`
+	for _, command := range g.commands {
+		if !isHidden(command) {
+			usage += alflag.FormatEntry(command.Name(), command.Description())
+		}
+	}
+	usage += alflag.FormatEntry(HelpCommandName, "Print the help message of a command")
+	return usage + `
+Run "` + g.Path() + ` help <command>" for more information on a command.
+`
+}
+
+func (g *Group) Run(args []string) (int, error) {
+	if err := g.flagset.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	if *g.help {
+		fmt.Fprintln(os.Stdout, strings.TrimSpace(g.Usage()))
+		return ExitCodeSuccess, nil
+	}
+
+	if g.flagset.NArg() == 0 {
+		if g.action != nil {
+			return g.action()
+		}
+		fmt.Fprintln(os.Stdout, strings.TrimSpace(g.Usage()))
+		return ExitCodeSuccess, nil
+	}
+
+	name := g.flagset.Arg(0)
+	if name == HelpCommandName {
+		return g.runHelp(g.flagset.Args()[1:])
+	}
+	command := g.Lookup(name)
+	if command == nil {
+		g.printUnknown(name)
+		return ExitCodeArgumentError, nil
+	}
+	return command.Run(g.flagset.Args()[1:])
+}
+
+// runHelp prints the usage of the subcommand at the path, e.g. "cache list", or the usage of the group if the
+// path is empty.
+func (g *Group) runHelp(path []string) (int, error) {
+	var command Command = g
+	for _, name := range path {
+		group, ok := command.(*Group)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "emit: '%s' has no subcommands\n", command.Name())
+			return ExitCodeArgumentError, nil
+		}
+		if command = group.Lookup(name); command == nil {
+			group.printUnknown(name)
+			return ExitCodeArgumentError, nil
+		}
+	}
+	fmt.Fprintln(os.Stdout, strings.TrimSpace(command.Usage()))
+	return ExitCodeSuccess, nil
+}
+
+// printUnknown prints the error of an unknown subcommand with the similar subcommands.
+func (g *Group) printUnknown(name string) {
+	message := fmt.Sprintf("emit: '%s' is not a valid command", name)
+	if g.parent != nil {
+		message += fmt.Sprintf(" of '%s'", g.Path())
+	}
+
+	names := []string{HelpCommandName}
+	for _, command := range g.commands {
+		if !isHidden(command) {
+			names = append(names, command.Name())
+		}
+	}
+	if suggestions := alflag.Suggest(name, names); len(suggestions) != 0 {
+		message += fmt.Sprintf(", did you mean '%s'?", suggestions[0])
+	}
+	fmt.Fprintln(os.Stderr, message)
+}
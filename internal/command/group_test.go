@@ -0,0 +1,230 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sotvokun/emit/internal/pkg/alflag"
+)
+
+// fakeCommand is a command recording the positional arguments of its last run.
+type fakeCommand struct {
+	flagset *alflag.FlagSet
+	hidden  bool
+	// usage is the help message, "Usage: <name>" if empty
+	usage string
+	// args are the positional arguments of the last run
+	args []string
+}
+
+func newFakeCommand(name string) *fakeCommand {
+	return &fakeCommand{flagset: alflag.NewFlagSet(name)}
+}
+
+func (f *fakeCommand) Name() string {
+	return f.flagset.Name()
+}
+
+func (f *fakeCommand) Description() string {
+	return "The " + f.Name() + " command"
+}
+
+func (f *fakeCommand) Usage() string {
+	if f.usage != "" {
+		return f.usage
+	}
+	return "Usage: " + f.Name()
+}
+
+func (f *fakeCommand) FlagSet() *alflag.FlagSet {
+	return f.flagset
+}
+
+func (f *fakeCommand) Hidden() bool {
+	return f.hidden
+}
+
+func (f *fakeCommand) Run(args []string) (int, error) {
+	if err := f.flagset.Parse(args); err != nil {
+		return parseError(err)
+	}
+	f.args = f.flagset.Args()
+	return ExitCodeSuccess, nil
+}
+
+// newTestTree returns the command tree "emit {clone, cache {list}, __hidden}" used by the tests.
+func newTestTree() *Group {
+	clone := newFakeCommand("clone")
+	clone.flagset.Bool("q, quiet", false, "Quiet")
+	clone.flagset.String("i", "", "The `path` of the identity")
+	clone.flagset.String("mode", "git", "The `mode`")
+	clone.flagset.String("o, output", "", "The `file`")
+	clone.flagset.SetEnum("mode", "git", "tar")
+	clone.flagset.MarkFilename("i")
+
+	list := newFakeCommand("list")
+	list.flagset.Bool("a, all", false, "All")
+	// The flag shadows the persistent flag of the root
+	list.flagset.String("config", "", "The `name`")
+
+	hidden := newFakeCommand("__hidden")
+	hidden.hidden = true
+
+	root := NewGroup("emit", "The root", clone, NewGroup("cache", "The cache", list), hidden)
+	root.FlagSet().Bool("v, verbose", false, "Verbose")
+	root.FlagSet().String("config", "", "The `path` of the config")
+	root.FlagSet().MarkFilename("config")
+	root.FlagSet().MarkPersistent("verbose", "config")
+	return root
+}
+
+// captureOutput runs fn with the standard output and error redirected, and returns what was written to them.
+func captureOutput(t *testing.T, fn func()) (string, string) {
+	t.Helper()
+
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+	}()
+
+	files := make([]*os.File, 2)
+	for i := range files {
+		f, err := os.CreateTemp(t.TempDir(), "output")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		files[i] = f
+	}
+	os.Stdout, os.Stderr = files[0], files[1]
+	fn()
+
+	var outputs []string
+	for _, f := range files {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, string(data))
+	}
+	return outputs[0], outputs[1]
+}
+
+func TestGroup_Run(t *testing.T) {
+	type testcase struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}
+
+	tests := []testcase{
+		{nil, ExitCodeSuccess, "Usage: emit [OPTIONS] <command> [<arguments>]", ""},
+		{[]string{"-h"}, ExitCodeSuccess, "Usage: emit [OPTIONS] <command> [<arguments>]", ""},
+		{[]string{"cache"}, ExitCodeSuccess, "Usage: emit cache [OPTIONS] <command> [<arguments>]", ""},
+		{[]string{"-v", "cache", "--help"}, ExitCodeSuccess, "Usage: emit cache [OPTIONS] <command> [<arguments>]", ""},
+
+		// The help command routes to the usage of the command at the path
+		{[]string{"help"}, ExitCodeSuccess, "Usage: emit [OPTIONS] <command> [<arguments>]", ""},
+		{[]string{"help", "clone"}, ExitCodeSuccess, "Usage: clone", ""},
+		{[]string{"help", "cache"}, ExitCodeSuccess, "Usage: emit cache [OPTIONS] <command> [<arguments>]", ""},
+		{[]string{"help", "cache", "list"}, ExitCodeSuccess, "Usage: list", ""},
+		{[]string{"cache", "help", "list"}, ExitCodeSuccess, "Usage: list", ""},
+		{[]string{"help", "clone", "list"}, ExitCodeArgumentError, "", "emit: 'clone' has no subcommands"},
+
+		// Unknown subcommands are reported with the similar visible ones
+		{[]string{"clnoe"}, ExitCodeArgumentError, "", "emit: 'clnoe' is not a valid command, did you mean 'clone'?"},
+		{[]string{"hlep"}, ExitCodeArgumentError, "", "emit: 'hlep' is not a valid command, did you mean 'help'?"},
+		{[]string{"__hiden"}, ExitCodeArgumentError, "", "emit: '__hiden' is not a valid command"},
+		{[]string{"cache", "lst"}, ExitCodeArgumentError, "", "emit: 'lst' is not a valid command of 'emit cache', did you mean 'list'?"},
+		{[]string{"help", "cache", "lst"}, ExitCodeArgumentError, "", "emit: 'lst' is not a valid command of 'emit cache', did you mean 'list'?"},
+		{[]string{"--unknown"}, ExitCodeArgumentError, "", "emit: unknown flag --unknown for the emit command"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.args), func(t *testing.T) {
+			root := newTestTree()
+			var code int
+			var err error
+			stdout, stderr := captureOutput(t, func() {
+				code, err = root.Run(test.args)
+			})
+			if err != nil || code != test.code {
+				t.Fatalf("Run(%q) = %d, %v; want %d, nil", test.args, code, err, test.code)
+			}
+			if firstLine(stdout) != test.stdout || firstLine(stderr) != test.stderr {
+				t.Errorf("Run(%q) output = %q, %q; want %q, %q",
+					test.args, firstLine(stdout), firstLine(stderr), test.stdout, test.stderr)
+			}
+		})
+	}
+}
+
+func TestGroup_Run_nested(t *testing.T) {
+	root := newTestTree()
+	cache := root.Lookup("cache").(*Group)
+	list := cache.Lookup("list").(*fakeCommand)
+
+	code, err := root.Run([]string{"-v", "cache", "list", "--config=name", "-a", "arg"})
+	if err != nil || code != ExitCodeSuccess {
+		t.Fatalf("Run() = %d, %v; want success", code, err)
+	}
+	if !reflect.DeepEqual(list.args, []string{"arg"}) {
+		t.Errorf("list args = %q; want [arg]", list.args)
+	}
+
+	// The --config flag of list shadows the persistent flag of the root
+	sources := map[*alflag.FlagSet]map[string]alflag.ValueSource{
+		root.FlagSet(): {"verbose": alflag.SourceFlag, "config": alflag.SourceDefault},
+		list.FlagSet(): {"all": alflag.SourceFlag, "config": alflag.SourceFlag},
+	}
+	for flagset, want := range sources {
+		for name, source := range want {
+			if got, _ := flagset.Source(name); got != source {
+				t.Errorf("%s: Source(%q) = %v; want %v", flagset.Name(), name, got, source)
+			}
+		}
+	}
+}
+
+func TestGroup_Usage(t *testing.T) {
+	cache := newTestTree().Lookup("cache").(*Group)
+	want := `
+Usage: emit cache [OPTIONS] <command> [<arguments>]
+
+The cache
+
+OPTIONS:
+    -h, --help                 Print this help message and exit
+
+GLOBAL OPTIONS:
+    -v, --verbose              Verbose
+    --config <path>            The path of the config
+
+COMMANDS:
+    list                       The list command
+    help                       Print the help message of a command
+
+Run "emit cache help <command>" for more information on a command.
+`
+	if got := cache.Usage(); got != want {
+		t.Errorf("Usage() =\n%s\nwant\n%s", got, want)
+	}
+
+	if usage := newTestTree().Usage(); strings.Contains(usage, "__hidden") {
+		t.Errorf("Usage() lists the hidden command:\n%s", usage)
+	}
+}
+
+// firstLine returns the first line of the output without the leading and trailing spaces.
+func firstLine(output string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	return line
+}
@@ -0,0 +1,249 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/sotvokun/emit/internal/pkg/alflag"
+	"github.com/sotvokun/emit/internal/pkg/semver"
+	"github.com/sotvokun/emit/pkg/degit"
+)
+
+type RefsCommand struct {
+	flagset *alflag.FlagSet
+
+	help     *bool
+	tags     *bool
+	branches *bool
+	sort     *string
+	json     *bool
+
+	remote *remoteOptions
+}
+
+// refsEntry is a branch or tag of the remote, the fields are exported for the JSON output.
+type refsEntry struct {
+	Name   string `json:"name"`
+	Ref    string `json:"ref"`
+	Type   string `json:"type"`
+	Commit string `json:"commit"`
+	Head   bool   `json:"head"`
+}
+
+func NewRefsCommand() *RefsCommand {
+	flagset := alflag.NewFlagSet("refs")
+	help := flagset.Bool("h, help", false, "Print this help message and exit")
+	flagset.SetEnvPrefix(envPrefix(flagset.Name()))
+
+	tags := flagset.Bool("t, tags", false, "List the tags only")
+	branches := flagset.Bool("b, branches", false, "List the branches only")
+	sort := flagset.String("sort", "name", "Sort the references by the `key`: name, version")
+	jsonOutput := flagset.Bool("json", false, "Print the references as a JSON array")
+
+	remote := newRemoteOptions(flagset)
+
+	flagset.SetEnum("sort", "name", "version")
+
+	return &RefsCommand{
+		flagset: flagset,
+
+		help:     help,
+		tags:     tags,
+		branches: branches,
+		sort:     sort,
+		json:     jsonOutput,
+
+		remote: remote,
+	}
+}
+
+func (r *RefsCommand) Name() string {
+	return "refs"
+}
+
+func (r *RefsCommand) Description() string {
+	return "List the branches and tags of a remote repository"
+}
+
+func (r *RefsCommand) FlagSet() *alflag.FlagSet {
+	return r.flagset
+}
+
+func (r *RefsCommand) Usage() string {
+	return `
+Usage: emit refs [OPTIONS] <remote> [<pattern>...]
+
+` + r.flagset.FlagUsages() + `
+ARGUMENTS:
+` + alflag.FormatEntry("<remote>", "The remote URL of a Git repository, the same shortcuts as \"emit degit\" are supported") +
+		alflag.FormatEntry("<pattern>", "(OPTIONAL) Glob patterns to filter the references by their short or full name") + `
+The default branch of the remote is marked with "*".
+The authentication options work the same as "emit degit", see "emit degit --help" for details.
+`
+}
+
+func (r *RefsCommand) Run(args []string) (int, error) {
+	if err := r.flagset.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	if *r.help {
+		fmt.Fprintln(os.Stdout, strings.TrimSpace(r.Usage()))
+		return ExitCodeSuccess, nil
+	}
+
+	if r.flagset.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "emit: missing remote")
+		return ExitCodeArgumentError, nil
+	}
+	patterns := r.flagset.Args()[1:]
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "emit: invalid pattern '%s'\n", pattern)
+			return ExitCodeArgumentError, nil
+		}
+	}
+
+	d, err := degit.New(degit.Options{
+		Remote:  expandRemote(r.flagset.Arg(0)),
+		Auth:    r.remote.auth(),
+		Retries: *r.remote.retries,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "emit: failed to create degit service")
+		return ExitCodeInternalError, err
+	}
+
+	ctx, cancel := r.remote.context()
+	defer cancel()
+
+	refs, err := d.References(ctx)
+	if err != nil {
+		if !r.remote.printContextError(err) {
+			fmt.Fprintln(os.Stderr, "emit: failed to list the references")
+		}
+		return ExitCodeInternalError, err
+	}
+
+	entries := r.filter(r.entries(refs), patterns)
+	r.sortEntries(entries)
+
+	if *r.json {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(entries); err != nil {
+			return ExitCodeInternalError, err
+		}
+		return ExitCodeSuccess, nil
+	}
+
+	for _, entry := range entries {
+		marker := " "
+		if entry.Head {
+			marker = "*"
+		}
+		fmt.Fprintf(os.Stdout, "%s %s %-6s %s\n", marker, entry.Commit, entry.Type, entry.Name)
+	}
+	return ExitCodeSuccess, nil
+}
+
+// entries converts the branches and tags to entries. The commit of an annotated tag is taken from its peeled
+// reference, and the branch which the symbolic HEAD points to is marked.
+func (r *RefsCommand) entries(refs []*plumbing.Reference) []refsEntry {
+	peeled := make(map[plumbing.ReferenceName]plumbing.Hash)
+	var head *plumbing.Reference
+	for _, ref := range refs {
+		if degit.IsPeeled(ref.Name()) {
+			name := strings.TrimSuffix(ref.Name().String(), degit.PeeledSuffix)
+			peeled[plumbing.ReferenceName(name)] = ref.Hash()
+		}
+		if ref.Name() == plumbing.HEAD {
+			head = ref
+		}
+	}
+
+	var entries []refsEntry
+	for _, ref := range refs {
+		var typ string
+		switch {
+		case ref.Name().IsBranch():
+			typ = "branch"
+		case ref.Name().IsTag() && !degit.IsPeeled(ref.Name()):
+			typ = "tag"
+		default:
+			continue
+		}
+
+		commit := ref.Hash()
+		if hash, ok := peeled[ref.Name()]; ok {
+			commit = hash
+		}
+
+		entries = append(entries, refsEntry{
+			Name:   ref.Name().Short(),
+			Ref:    ref.Name().String(),
+			Type:   typ,
+			Commit: commit.String(),
+			// A detached HEAD marks no branch, even those pointing to its commit
+			Head: head != nil && head.Type() == plumbing.SymbolicReference && head.Target() == ref.Name(),
+		})
+	}
+	return entries
+}
+
+// filter returns the entries selected by the "--tags" and "--branches" options, and matching any of the patterns.
+func (r *RefsCommand) filter(entries []refsEntry, patterns []string) []refsEntry {
+	filtered := make([]refsEntry, 0, len(entries))
+	for _, entry := range entries {
+		if (*r.tags || *r.branches) && !(*r.tags && entry.Type == "tag" || *r.branches && entry.Type == "branch") {
+			continue
+		}
+		if len(patterns) != 0 && !slices.ContainsFunc(patterns, func(pattern string) bool {
+			short, _ := path.Match(pattern, entry.Name)
+			full, _ := path.Match(pattern, entry.Ref)
+			return short || full
+		}) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// sortEntries sorts the entries by the "--sort" option. For the version order, the names which are not
+// semantic versions are placed after the versions and sorted by name.
+func (r *RefsCommand) sortEntries(entries []refsEntry) {
+	slices.SortStableFunc(entries, func(a, b refsEntry) int {
+		if *r.sort == "version" {
+			av, aErr := semver.Parse(a.Name)
+			bv, bErr := semver.Parse(b.Name)
+			switch {
+			case aErr == nil && bErr == nil:
+				if c := av.Compare(bv); c != 0 {
+					return c
+				}
+			case aErr == nil:
+				return -1
+			case bErr == nil:
+				return 1
+			}
+		}
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Ref, b.Ref)
+	})
+}
+
+// CompleteArgs completes the local path of the remote.
+func (r *RefsCommand) CompleteArgs(args []string, word string) ([]string, Directive) {
+	if len(args) == 0 {
+		return nil, DirectiveFiles
+	}
+	return nil, 0
+}
@@ -0,0 +1,76 @@
+package command
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestRefsCommand_entries(t *testing.T) {
+	refs := []*plumbing.Reference{
+		plumbing.NewReferenceFromStrings("refs/heads/main", "1110000000000000000000000000000000000000"),
+		plumbing.NewReferenceFromStrings("refs/heads/release", "1110000000000000000000000000000000000000"),
+		plumbing.NewReferenceFromStrings("refs/heads/dev", "2220000000000000000000000000000000000000"),
+		plumbing.NewReferenceFromStrings("refs/tags/v1.0.0", "3330000000000000000000000000000000000000"),
+		plumbing.NewReferenceFromStrings("refs/tags/v1.0.0^{}", "1110000000000000000000000000000000000000"),
+		plumbing.NewReferenceFromStrings("refs/tags/v1.10.0", "4440000000000000000000000000000000000000"),
+		plumbing.NewReferenceFromStrings("refs/tags/v1.2.0", "5550000000000000000000000000000000000000"),
+		plumbing.NewReferenceFromStrings("refs/pull/1/head", "6660000000000000000000000000000000000000"),
+	}
+
+	type testcase struct {
+		head *plumbing.Reference
+		args []string
+		want []string
+	}
+
+	tests := []testcase{
+		// Only the branch which the symbolic HEAD points to is marked
+		{
+			plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/main"),
+			nil,
+			[]string{"branch dev 222", "branch main 111 *", "branch release 111", "tag v1.0.0 111", "tag v1.10.0 444", "tag v1.2.0 555"},
+		},
+		// A detached HEAD marks no branch
+		{
+			plumbing.NewReferenceFromStrings("HEAD", "1110000000000000000000000000000000000000"),
+			nil,
+			[]string{"branch dev 222", "branch main 111", "branch release 111", "tag v1.0.0 111", "tag v1.10.0 444", "tag v1.2.0 555"},
+		},
+		{nil, []string{"--tags", "--sort=version"}, []string{"tag v1.0.0 111", "tag v1.2.0 555", "tag v1.10.0 444"}},
+		{nil, []string{"--branches"}, []string{"branch dev 222", "branch main 111", "branch release 111"}},
+		{nil, []string{"-t", "-b", "--sort=version"}, []string{"tag v1.0.0 111", "tag v1.2.0 555", "tag v1.10.0 444", "branch dev 222", "branch main 111", "branch release 111"}},
+		{nil, []string{"v1.*", "refs/heads/d*"}, []string{"branch dev 222", "tag v1.0.0 111", "tag v1.10.0 444", "tag v1.2.0 555"}},
+		{nil, []string{"pull/*"}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.args), func(t *testing.T) {
+			r := NewRefsCommand()
+			if err := r.flagset.Parse(append([]string{"remote"}, test.args...)); err != nil {
+				t.Fatal(err)
+			}
+
+			all := refs
+			if test.head != nil {
+				all = append([]*plumbing.Reference{test.head}, refs...)
+			}
+			entries := r.filter(r.entries(all), r.flagset.Args()[1:])
+			r.sortEntries(entries)
+
+			var got []string
+			for _, entry := range entries {
+				line := fmt.Sprintf("%s %s %s", entry.Type, entry.Name, entry.Commit[:3])
+				if entry.Head {
+					line += " *"
+				}
+				got = append(got, line)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("entries = %q; want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sotvokun/emit/internal/pkg/alflag"
+	"github.com/sotvokun/emit/pkg/degit"
+)
+
+// completeRefsTimeout is the time limit of listing the references of a remote for the shell completion.
+const completeRefsTimeout = 5 * time.Second
+
+var (
+	DegitCommandRemoteGitHubShortcutRegexp = regexp.MustCompile(`^([a-zA-Z0-9\_\.\-]+)\/([a-zA-Z0-9\_\.\-]+)$`)
+)
+
+// remoteOptions holds the options shared by the commands which access a remote repository.
+type remoteOptions struct {
+	identity  *string
+	username  *string
+	secrets   *string
+	noSecrets *bool
+
+	timeout *time.Duration
+	retries *int
+}
+
+// newRemoteOptions registers the options in the "AUTHENTICATION OPTIONS" and "NETWORK OPTIONS" groups,
+// the flags registered after them are in the latter group. The authentication options are also bound to the
+// environment variables shared by the commands, e.g. "EMIT_IDENTITY".
+func newRemoteOptions(flagset *alflag.FlagSet) *remoteOptions {
+	r := &remoteOptions{}
+
+	flagset.SetGroup("AUTHENTICATION OPTIONS")
+	r.identity = flagset.String("i", "", "The `path` to the identity file for the SSH authentication")
+	r.username = flagset.String("l", "", "The `username` to use for the authentication")
+	r.secrets = flagset.String("p", "", "The `secrets` of the authentication: the password for the basic authentication, or the passphrase for the public key authentication")
+	r.noSecrets = flagset.Bool("no-secrets", false, "Skip the interactive secrets prompt for the authentication")
+
+	// The short options have no derived environment variables
+	prefix := envPrefix(flagset.Name())
+	flagset.BindEnv("i", prefix+"_IDENTITY", EnvPrefix+"_IDENTITY")
+	flagset.BindEnv("l", prefix+"_USERNAME", EnvPrefix+"_USERNAME")
+	flagset.BindEnv("p", prefix+"_SECRETS", EnvPrefix+"_SECRETS")
+
+	flagset.SetGroup("NETWORK OPTIONS")
+	r.timeout = flagset.Duration("timeout", 0, "Abort the command if it takes longer than the `duration` (e.g. 30s, 5m)")
+	r.retries = flagset.Int("retries", 0, "Retry network operations on transient errors with exponential backoff, up to `count` times")
+
+	flagset.MarkFilename("i")
+	flagset.MarkRequires("p", "l", "i")
+	flagset.MarkExclusive("no-secrets", "p")
+	flagset.AddValidator("retries", func() error {
+		if *r.retries < 0 {
+			return fmt.Errorf("must not be negative")
+		}
+		return nil
+	})
+	return r
+}
+
+// context returns a context which is canceled by SIGINT, SIGTERM or the "--timeout" option.
+func (r *remoteOptions) context() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if *r.timeout <= 0 {
+		return ctx, stop
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *r.timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// auth returns the authentication selected by the options, the secrets are prompted if needed.
+// `nil` is returned if no authentication is selected.
+func (r *remoteOptions) auth() *degit.Auth {
+	if len(*r.identity) != 0 {
+		if len(*r.username) == 0 {
+			*r.username = "git"
+		}
+		passphrase := ""
+		if len(*r.secrets) != 0 {
+			passphrase = *r.secrets
+		}
+		if *r.username == "git" || (len(passphrase) == 0 && !*r.noSecrets) {
+			passphrase = promptSecret("Passphrase: ")
+		}
+		return &degit.Auth{Username: *r.username, Password: passphrase, IdentityFile: *r.identity}
+	}
+
+	if len(*r.username) != 0 {
+		password := ""
+		if len(*r.secrets) != 0 {
+			password = *r.secrets
+		}
+		if len(password) == 0 && !*r.noSecrets {
+			password = promptSecret("Password: ")
+		}
+		return &degit.Auth{Username: *r.username, Password: password}
+	}
+	return nil
+}
+
+// promptSecret prints the prompt and reads the secret from the terminal, so the prompt is never mixed into an
+// archive written to stdout. The standard error and the standard input are used if there is no terminal.
+func promptSecret(prompt string) string {
+	secret := ""
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		defer tty.Close()
+		fmt.Fprint(tty, prompt)
+		fmt.Fscanln(tty, &secret)
+		return secret
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	fmt.Scanln(&secret)
+	return secret
+}
+
+// expandRemote expands the GitHub shortcut "user/repo" to the full remote URL.
+// A local path or an archive is never expanded, e.g. "../template" or "dist/starter.tar.gz".
+func expandRemote(remote string) string {
+	if _, ok := degit.LocalPath(remote); ok || len(degit.ArchiveFormat(remote)) != 0 {
+		return remote
+	}
+	if DegitCommandRemoteGitHubShortcutRegexp.MatchString(remote) {
+		return fmt.Sprintf("https://github.com/%s.git", remote)
+	}
+	return remote
+}
+
+// completeRefs returns the branches and tags of the remote starting with the ref of the word "<remote>#<ref>",
+// as the words of the same form. The remote is accessed without the authentication options, and no candidate
+// is returned if the references cannot be listed in time.
+func completeRefs(word string) []string {
+	remote, prefix, _ := strings.Cut(word, "#")
+	d, err := degit.New(degit.Options{Remote: expandRemote(remote)})
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completeRefsTimeout)
+	defer cancel()
+	refs, err := d.References(ctx)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	for _, ref := range refs {
+		name := ref.Name()
+		if !name.IsBranch() && (!name.IsTag() || degit.IsPeeled(name)) {
+			continue
+		}
+		if short := name.Short(); strings.HasPrefix(short, prefix) {
+			candidates = append(candidates, remote+"#"+short)
+		}
+	}
+	return candidates
+}
+
+// printContextError prints a message for the errors caused by the cancellation of the context returned by
+// [remoteOptions.context], and reports whether the error is one of them.
+func (r *remoteOptions) printContextError(err error) bool {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		fmt.Fprintf(os.Stderr, "emit: timed out after %s\n", *r.timeout)
+	case errors.Is(err, context.Canceled):
+		fmt.Fprintln(os.Stderr, "emit: interrupted")
+	default:
+		return false
+	}
+	return true
+}
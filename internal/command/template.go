@@ -0,0 +1,78 @@
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/sotvokun/emit/pkg/degit"
+)
+
+// lockOptions returns the degit options fetching the template recorded in the lock file, with the modes of the
+// clone and the authentication of the remote options. The default modes are used if the lock does not record them.
+func lockOptions(lock *degit.Lock, remote *remoteOptions) (degit.Options, error) {
+	options := degit.Options{
+		Remote:         lock.Remote,
+		Ref:            lock.Ref,
+		Include:        lock.Include,
+		Exclude:        lock.Exclude,
+		SubmodulePaths: lock.SubmodulePaths,
+		Retries:        *remote.retries,
+	}
+
+	var err error
+	if len(lock.Mode) != 0 {
+		if options.Mode, err = degit.ParseMode(lock.Mode); err != nil {
+			return options, err
+		}
+	}
+	if len(lock.Submodules) != 0 {
+		if options.Submodules, err = degit.ParseSubmoduleMode(lock.Submodules); err != nil {
+			return options, err
+		}
+	}
+	if len(lock.LFS) != 0 {
+		if options.LFS, err = degit.ParseLFSMode(lock.LFS); err != nil {
+			return options, err
+		}
+	}
+
+	options.Auth = remote.auth()
+	return options, nil
+}
+
+// fetchTemplate clones the files of the resolution into an in-memory filesystem.
+func fetchTemplate(ctx context.Context, options degit.Options, resolution *degit.Resolution) (billy.Filesystem, error) {
+	dest := degit.NewMemoryDestination()
+	options.Destination = dest
+	if resolution.Reference == nil {
+		options.LockFile = ""
+	}
+
+	dg, err := degit.New(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := dg.CloneResolution(ctx, resolution); err != nil {
+		return nil, err
+	}
+	return dest.Filesystem(), nil
+}
+
+// writeLock replaces the lock file of the destination.
+func writeLock(destDir string, path string, data []byte) error {
+	path = filepath.Join(destDir, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// shortHash returns the abbreviated commit hash shown in the messages.
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,281 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/sotvokun/emit/internal/pkg/alflag"
+	"github.com/sotvokun/emit/internal/service/update"
+	"github.com/sotvokun/emit/pkg/degit"
+)
+
+type UpdateCommand struct {
+	flagset *alflag.FlagSet
+
+	help     *bool
+	dryRun   *bool
+	verbose  *bool
+	ref      *string
+	lockFile *string
+	conflict *string
+
+	remote *remoteOptions
+}
+
+func NewUpdateCommand() *UpdateCommand {
+	flagset := alflag.NewFlagSet("update")
+	help := flagset.Bool("h, help", false, "Print this help message and exit")
+	flagset.SetEnvPrefix(envPrefix(flagset.Name()))
+	dryRun := flagset.Bool("dry-run", false, "Print the changes without writing them")
+	verbose := flagset.Bool("v, verbose", false, "Enable verbose output")
+	flagset.BindEnv("verbose", EnvPrefix+"_VERBOSE")
+	ref := flagset.String("ref", "", "Update to the `ref` instead of the ref recorded in the lock file, "+
+		"e.g. a branch, a tag or a selector like \"latest\" or \"^2\"")
+	lockFile := flagset.String("lock-file", degit.DefaultLockFile, "The `path` of the lock file in the destination")
+	conflict := flagset.String("conflict", string(update.ConflictMarkers),
+		"How to write the conflicts of a text file, the `style` is markers or rej\n"+
+			"The \"markers\" style writes both sides between conflict markers, the \"rej\" style keeps the project "+
+			"side and writes the template side into a .rej file")
+
+	remote := newRemoteOptions(flagset)
+
+	flagset.SetEnum("conflict", string(update.ConflictMarkers), string(update.ConflictReject))
+
+	return &UpdateCommand{
+		flagset: flagset,
+
+		help:     help,
+		dryRun:   dryRun,
+		verbose:  verbose,
+		ref:      ref,
+		lockFile: lockFile,
+		conflict: conflict,
+
+		remote: remote,
+	}
+}
+
+func (u *UpdateCommand) Name() string {
+	return "update"
+}
+
+func (u *UpdateCommand) Description() string {
+	return "Merge the changes of the template into a project created by degit"
+}
+
+func (u *UpdateCommand) FlagSet() *alflag.FlagSet {
+	return u.flagset
+}
+
+func (u *UpdateCommand) Usage() string {
+	return `
+Usage: emit update [OPTIONS] [<destination>]
+
+Merge the changes of the template since the commit recorded in the lock file into the destination.
+The destination must have been created by "emit degit --lock".
+
+` + u.flagset.FlagUsages() + `
+ARGUMENTS:
+` + alflag.FormatEntry("<destination>", "(OPTIONAL) The directory created from the template\n"+
+		"Use the current directory if not specified") + `
+CHANGES:
+    A    The file was added by the template
+    M    The file was changed by the template, or merged with the changes of the project
+    D    The file was deleted by the template
+    C    The file was changed on both sides and has conflicts to resolve
+
+    The files which the template does not know about are never touched. The command exits with
+    the code 3 if there are conflicts.
+`
+}
+
+func (u *UpdateCommand) Run(args []string) (int, error) {
+	if err := u.flagset.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	if *u.help {
+		fmt.Fprintln(os.Stdout, strings.TrimSpace(u.Usage()))
+		return ExitCodeSuccess, nil
+	}
+
+	style, err := update.ParseConflictStyle(*u.conflict)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "emit: %v\n", err)
+		return ExitCodeArgumentError, nil
+	}
+
+	destDir := "."
+	if u.flagset.NArg() >= 1 {
+		destDir = u.flagset.Arg(0)
+	}
+
+	lock, err := degit.ReadLock(filepath.Join(destDir, filepath.FromSlash(*u.lockFile)))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "emit: failed to read the lock file")
+		return ExitCodeInternalError, err
+	}
+	if len(lock.Commit) == 0 {
+		fmt.Fprintln(os.Stderr, "emit: the template has no commit to update from, archive sources cannot be updated")
+		return ExitCodeArgumentError, nil
+	}
+
+	options, err := lockOptions(lock, u.remote)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "emit: invalid lock file")
+		return ExitCodeInternalError, err
+	}
+	if len(*u.ref) != 0 {
+		options.Ref = *u.ref
+	}
+	options.LockFile = *u.lockFile
+	var logger *log.Logger
+	if *u.verbose {
+		logger = log.New(os.Stdout, "", log.LstdFlags)
+		options.Logger = logger
+		logEnvSources(logger, u.flagset)
+	}
+
+	ctx, cancel := u.remote.context()
+	defer cancel()
+
+	dg, err := degit.New(options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "emit: failed to create degit service")
+		return ExitCodeInternalError, err
+	}
+	resolution, err := dg.Resolve(ctx)
+	if err != nil {
+		if !u.remote.printContextError(err) {
+			fmt.Fprintln(os.Stderr, "emit: failed to resolve the reference")
+		}
+		return ExitCodeInternalError, err
+	}
+	// A new ref resolving to the same commit is recorded by the update, which has no changes then
+	if resolution.Commit.String() == lock.Commit && options.Ref == lock.Ref {
+		fmt.Fprintf(os.Stdout, "emit: already up to date (commit %s)\n", lock.Commit)
+		return ExitCodeSuccess, nil
+	}
+
+	// The old template is fetched by the commit since its reference may have moved
+	base, err := fetchTemplate(ctx, options, &degit.Resolution{
+		Requested: lock.Ref,
+		Commit:    plumbing.NewHash(lock.Commit),
+	})
+	if err != nil {
+		if !u.remote.printContextError(err) {
+			fmt.Fprintf(os.Stderr, "emit: failed to fetch the template at commit %s\n", lock.Commit)
+		}
+		return ExitCodeInternalError, err
+	}
+	template, err := fetchTemplate(ctx, options, resolution)
+	if err != nil {
+		if !u.remote.printContextError(err) {
+			fmt.Fprintf(os.Stderr, "emit: failed to fetch the template at commit %s\n", resolution.Commit)
+		}
+		return ExitCodeInternalError, err
+	}
+
+	// The new lock file is written by the clone of the new template, it replaces the old one after the update
+	lockPath := filepath.FromSlash(*u.lockFile)
+	lockData, err := util.ReadFile(template, lockPath)
+	if err != nil {
+		return ExitCodeInternalError, err
+	}
+	if err := template.Remove(lockPath); err != nil {
+		return ExitCodeInternalError, err
+	}
+
+	project := osfs.New(destDir)
+	service := update.NewUpdateService(project)
+	if logger != nil {
+		service.SetLogger(logger)
+	}
+	service.SetDryMode(*u.dryRun)
+	service.SetConflictStyle(style)
+	service.SetLabels("project", "template "+shortHash(resolution.Commit.String()))
+
+	changes, err := service.Update(base, template)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "emit: failed to update the files")
+		return ExitCodeInternalError, err
+	}
+
+	if !*u.dryRun {
+		if err := writeUpdateLock(destDir, lockPath, lockData, lock, changes, project); err != nil {
+			fmt.Fprintln(os.Stderr, "emit: failed to write the lock file")
+			return ExitCodeInternalError, err
+		}
+	}
+
+	counts := make(map[update.Status]int)
+	for _, change := range changes {
+		counts[change.Status]++
+		if len(change.Detail) != 0 {
+			fmt.Fprintf(os.Stdout, "%c %s (%s)\n", change.Status, change.Path, change.Detail)
+		} else {
+			fmt.Fprintf(os.Stdout, "%c %s\n", change.Status, change.Path)
+		}
+	}
+
+	verb := "updated"
+	if *u.dryRun {
+		verb = "would update"
+	}
+	fmt.Fprintf(os.Stdout, "emit: %s from %s to %s: %d added, %d modified, %d deleted, %d conflicts\n",
+		verb, shortHash(lock.Commit), shortHash(resolution.Commit.String()),
+		counts[update.StatusAdded], counts[update.StatusModified], counts[update.StatusDeleted], counts[update.StatusConflict])
+
+	if counts[update.StatusConflict] != 0 {
+		return ExitCodeConflict, nil
+	}
+	return ExitCodeSuccess, nil
+}
+
+// writeUpdateLock writes the lock file of the new template with the hashes of the project files as the update left
+// them. A written file, e.g. merged or with conflict markers, is hashed as written, and a file which the update did
+// not write keeps its recorded hash unless it matches the new template.
+func writeUpdateLock(destDir string, path string, data []byte, old *degit.Lock, changes []update.Change, project billy.Filesystem) error {
+	lock, err := degit.ParseLock(data)
+	if err != nil {
+		return err
+	}
+	written := make(map[string]bool)
+	for _, change := range changes {
+		written[change.Path] = change.Written
+	}
+
+	for name, hash := range lock.Files {
+		current, err := degit.HashFile(project, filepath.FromSlash(name))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		switch {
+		case written[name], current == hash:
+			lock.Files[name] = current
+		case len(old.Files[name]) != 0:
+			lock.Files[name] = old.Files[name]
+		}
+	}
+
+	if data, err = lock.Marshal(); err != nil {
+		return err
+	}
+	return writeLock(destDir, path, data)
+}
+
+// CompleteArgs completes the destination.
+func (u *UpdateCommand) CompleteArgs(args []string, word string) ([]string, Directive) {
+	if len(args) == 0 {
+		return nil, DirectiveFiles
+	}
+	return nil, 0
+}
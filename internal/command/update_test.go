@@ -0,0 +1,77 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/sotvokun/emit/internal/service/update"
+	"github.com/sotvokun/emit/pkg/degit"
+)
+
+func TestWriteUpdateLock(t *testing.T) {
+	hash := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	project := memfs.New()
+	for name, content := range map[string]string{
+		"added.txt":  "new\n",
+		"merged.txt": "one\nfive\n",
+		"kept.txt":   "project\n",
+		"same.txt":   "template\n",
+		"edited.txt": "project\n",
+	} {
+		if err := util.WriteFile(project, name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := &degit.Lock{Version: degit.LockVersion, Files: map[string]string{
+		"merged.txt": hash("1\n5\n"),
+		"kept.txt":   hash("base\n"),
+		"same.txt":   hash("base\n"),
+		"edited.txt": hash("merged before\n"),
+	}}
+	template := &degit.Lock{Version: degit.LockVersion, Commit: "new", Files: map[string]string{
+		"added.txt":  hash("new\n"),
+		"merged.txt": hash("1\nfive\n"),
+		"kept.txt":   hash("template\n"),
+		"same.txt":   hash("template\n"),
+		"edited.txt": hash("template\n"),
+	}}
+	data, err := template.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := []update.Change{
+		{Path: "added.txt", Status: update.StatusAdded, Written: true},
+		{Path: "kept.txt", Status: update.StatusConflict},
+		{Path: "merged.txt", Status: update.StatusModified, Written: true},
+	}
+
+	dir := t.TempDir()
+	if err := writeUpdateLock(dir, "lock.json", data, old, changes, project); err != nil {
+		t.Fatal(err)
+	}
+	got, err := degit.ReadLock(filepath.Join(dir, "lock.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The written files are hashed as written, the others keep their recorded hash unless they match the template
+	want := map[string]string{
+		"added.txt":  hash("new\n"),
+		"merged.txt": hash("one\nfive\n"),
+		"kept.txt":   hash("base\n"),
+		"same.txt":   hash("template\n"),
+		"edited.txt": hash("merged before\n"),
+	}
+	if !reflect.DeepEqual(got.Files, want) || got.Commit != "new" {
+		t.Errorf("lock = %s %v; want new %v", got.Commit, got.Files, want)
+	}
+}
@@ -18,8 +18,9 @@ type VersionCommand struct {
 
 func NewVersionCommand() *VersionCommand {
 	flagset := alflag.NewFlagSet("version")
-	all := flagset.Bool("a, all", false)
-	help := flagset.Bool("h, help", false)
+	all := flagset.Bool("a, all", false, "Print the version with the commit and the build date")
+	help := flagset.Bool("h, help", false, "Print this help message and exit")
+	flagset.SetEnvPrefix(envPrefix(flagset.Name()))
 
 	return &VersionCommand{
 		flagset: flagset,
@@ -33,13 +34,24 @@ func (v *VersionCommand) Name() string {
 	return "version"
 }
 
+func (v *VersionCommand) Description() string {
+	return "Display version information about emit"
+}
+
+func (v *VersionCommand) FlagSet() *alflag.FlagSet {
+	return v.flagset
+}
+
 func (v *VersionCommand) Usage() string {
-	return "Usage: emit version [-a | --all]"
+	return `
+Usage: emit version [OPTIONS]
+
+` + v.flagset.FlagUsages()
 }
 
 func (v *VersionCommand) Run(args []string) (int, error) {
 	if err := v.flagset.Parse(args); err != nil {
-		return ExitCodeInternalError, err
+		return parseError(err)
 	}
 
 	if *v.help {
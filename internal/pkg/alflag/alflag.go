@@ -11,6 +11,10 @@ func init() {
 	flagset = NewFlagSet("")
 }
 
+func AddValidator(name string, validate func() error) {
+	flagset.AddValidator(name, validate)
+}
+
 func Arg(i int) string {
 	return flagset.Arg(i)
 }
@@ -19,6 +23,10 @@ func Args() []string {
 	return flagset.Args()
 }
 
+func BindEnv(name string, envs ...string) {
+	flagset.BindEnv(name, envs...)
+}
+
 func Bool(name string, value bool, usage ...string) *bool {
 	return flagset.Bool(name, value, usage...)
 }
@@ -39,6 +47,14 @@ func DurationVar(p *time.Duration, name string, value time.Duration, usage ...st
 	flagset.DurationVar(p, name, value, usage...)
 }
 
+func Flags() []Flag {
+	return flagset.Flags()
+}
+
+func FlagUsages() string {
+	return flagset.FlagUsages()
+}
+
 func Float64(name string, value float64, usage ...string) *float64 {
 	return flagset.Float64(name, value, usage...)
 }
@@ -51,6 +67,10 @@ func Func(name string, usage string, fn func(string) error) {
 	flagset.Func(name, usage, fn)
 }
 
+func InheritedFlags() []Flag {
+	return flagset.InheritedFlags()
+}
+
 func Init(name string) {
 	flagset.Init(name)
 }
@@ -71,6 +91,26 @@ func Int64Var(p *int64, name string, value int64, usage ...string) {
 	flagset.Int64Var(p, name, value, usage...)
 }
 
+func MarkExclusive(names ...string) {
+	flagset.MarkExclusive(names...)
+}
+
+func MarkFilename(names ...string) {
+	flagset.MarkFilename(names...)
+}
+
+func MarkPersistent(names ...string) {
+	flagset.MarkPersistent(names...)
+}
+
+func MarkRequired(names ...string) {
+	flagset.MarkRequired(names...)
+}
+
+func MarkRequires(name string, others ...string) {
+	flagset.MarkRequires(name, others...)
+}
+
 func NArg() int {
 	return flagset.NArg()
 }
@@ -91,10 +131,34 @@ func Parsed() bool {
 	return flagset.Parsed()
 }
 
+func SetEnum(name string, choices ...string) {
+	flagset.SetEnum(name, choices...)
+}
+
+func SetEnvPrefix(prefix string) {
+	flagset.SetEnvPrefix(prefix)
+}
+
+func SetGroup(title string) {
+	flagset.SetGroup(title)
+}
+
+func SetInterspersed(interspersed bool) {
+	flagset.SetInterspersed(interspersed)
+}
+
+func SetParent(parent *FlagSet) {
+	flagset.SetParent(parent)
+}
+
 func Set(name string, value string) error {
 	return flagset.Set(name, value)
 }
 
+func Source(name string) (ValueSource, string) {
+	return flagset.Source(name)
+}
+
 func String(name string, value string, usage ...string) *string {
 	return flagset.String(name, value, usage...)
 }
@@ -103,6 +167,38 @@ func StringVar(p *string, name string, value string, usage ...string) {
 	flagset.StringVar(p, name, value, usage...)
 }
 
+func StringMap(name string, value map[string]string, usage ...string) *map[string]string {
+	return flagset.StringMap(name, value, usage...)
+}
+
+func StringMapVar(p *map[string]string, name string, value map[string]string, usage ...string) {
+	flagset.StringMapVar(p, name, value, usage...)
+}
+
+func StringMapComma(name string, value map[string]string, usage ...string) *map[string]string {
+	return flagset.StringMapComma(name, value, usage...)
+}
+
+func StringMapCommaVar(p *map[string]string, name string, value map[string]string, usage ...string) {
+	flagset.StringMapCommaVar(p, name, value, usage...)
+}
+
+func StringSlice(name string, value []string, usage ...string) *[]string {
+	return flagset.StringSlice(name, value, usage...)
+}
+
+func StringSliceVar(p *[]string, name string, value []string, usage ...string) {
+	flagset.StringSliceVar(p, name, value, usage...)
+}
+
+func StringSliceComma(name string, value []string, usage ...string) *[]string {
+	return flagset.StringSliceComma(name, value, usage...)
+}
+
+func StringSliceCommaVar(p *[]string, name string, value []string, usage ...string) {
+	flagset.StringSliceCommaVar(p, name, value, usage...)
+}
+
 func TextVar(p encoding.TextUnmarshaler, name string, value encoding.TextMarshaler, usage ...string) {
 	flagset.TextVar(p, name, value, usage...)
 }
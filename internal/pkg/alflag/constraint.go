@@ -0,0 +1,219 @@
+package alflag
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrorKind is the kind of an [ArgumentError].
+type ErrorKind int
+
+const (
+	// ErrorSyntax is an unknown flag, a missing value or a value which cannot be parsed
+	ErrorSyntax ErrorKind = iota
+	// ErrorRequired is a required flag which is not given, see [FlagSet.MarkRequired]
+	ErrorRequired
+	// ErrorEnum is a value which is not one of the choices, see [FlagSet.SetEnum]
+	ErrorEnum
+	// ErrorExclusive is a group of flags which are given together, see [FlagSet.MarkExclusive]
+	ErrorExclusive
+	// ErrorRequires is a flag given without any flag it requires, see [FlagSet.MarkRequires]
+	ErrorRequires
+	// ErrorInvalid is a value rejected by a validator, see [FlagSet.AddValidator]
+	ErrorInvalid
+)
+
+// ArgumentError is returned by [FlagSet.Parse] if the arguments are invalid.
+type ArgumentError struct {
+	Kind ErrorKind
+	// Flags are the flags causing the error as shown in the help text, e.g. "--output" or "-p"
+	Flags []string
+	// Suggestions are the similar flags of an unknown flag, e.g. "--dry-run" for "--dryrun"
+	Suggestions []string
+	Err         error
+}
+
+func (e *ArgumentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ArgumentError) Unwrap() error {
+	return e.Err
+}
+
+// requirement is a flag which requires any of the other flags.
+type requirement struct {
+	name   string
+	others []string
+}
+
+// MarkRequired marks the registered flags as required, they must be given in the arguments or by their
+// environment variables.
+func (f *FlagSet) MarkRequired(names ...string) {
+	for _, name := range names {
+		f.mustLookupName(name).required = true
+	}
+}
+
+// SetEnum restricts the values of the registered flag to the choices, the default value is not checked.
+func (f *FlagSet) SetEnum(name string, choices ...string) {
+	f.mustLookupName(name).enum = choices
+}
+
+// MarkExclusive marks the registered flags as mutually exclusive, at most one of them can be given in the
+// arguments. The environment variables of the others are ignored if one of them is given in the arguments.
+func (f *FlagSet) MarkExclusive(names ...string) {
+	for _, name := range names {
+		f.mustLookupName(name)
+	}
+	f.exclusives = append(f.exclusives, names)
+}
+
+// MarkRequires marks that the registered flag can be given in the arguments only with any of the other flags,
+// which can be given in the arguments or by their environment variables.
+func (f *FlagSet) MarkRequires(name string, others ...string) {
+	f.mustLookupName(name)
+	for _, other := range others {
+		f.mustLookupName(other)
+	}
+	f.requirements = append(f.requirements, requirement{name: name, others: others})
+}
+
+// AddValidator adds a validator of the registered flag, which is called if the flag is given. The validator
+// usually reads the variable of the flag, and its error is reported with the flag and its value.
+func (f *FlagSet) AddValidator(name string, validate func() error) {
+	fn := f.mustLookupName(name)
+	fn.validators = append(fn.validators, validate)
+}
+
+// mustLookupName returns the registered flag with the short or long name, it panics if the flag is not
+// registered.
+func (f *FlagSet) mustLookupName(name string) *flagName {
+	fn := f.lookupName(name)
+	if fn == nil {
+		panic(fmt.Sprintf("flag is not defined: %s", name))
+	}
+	return fn
+}
+
+// given reports whether the flag is given in the arguments or by its environment variable.
+func (f *FlagSet) given(name string) bool {
+	source, _ := f.Source(name)
+	return source != SourceDefault
+}
+
+// givenFlag reports whether the flag is given in the arguments.
+func (f *FlagSet) givenFlag(name string) bool {
+	source, _ := f.Source(name)
+	return source == SourceFlag
+}
+
+// helpRequested reports whether the "help" flag is given in the arguments, the constraints and the environment
+// variables are ignored then, so the help text is printed whatever the environment is.
+func (f *FlagSet) helpRequested() bool {
+	fn := f.lookupName("help")
+	return fn != nil && fn.source == SourceFlag && f.flagset.Lookup(fn.name()).Value.String() == "true"
+}
+
+// excluded reports whether another flag of an exclusive group of the flag is given in the arguments, the
+// environment variable of the flag is ignored then, as the flags take precedence over the environment.
+func (f *FlagSet) excluded(fn *flagName) bool {
+	for _, names := range f.exclusives {
+		var member bool
+		for _, name := range names {
+			if f.lookupName(name) == fn {
+				member = true
+			}
+		}
+		if !member {
+			continue
+		}
+		for _, name := range names {
+			if f.lookupName(name) != fn && f.givenFlag(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validate checks the constraints of the flags after the parsing, in the order of the values, the required
+// flags, the exclusive groups and the requirements. The exclusive groups and the requirements apply to the
+// flags given in the arguments only, so an environment variable shared by several commands, e.g.
+// "EMIT_SECRETS", cannot break a command line which does not use it.
+func (f *FlagSet) validate() error {
+	for _, fn := range f.names {
+		if fn.source == SourceDefault {
+			continue
+		}
+		value := f.flagset.Lookup(fn.name()).Value.String()
+		if len(fn.enum) != 0 && !slices.Contains(fn.enum, value) {
+			return &ArgumentError{
+				Kind:  ErrorEnum,
+				Flags: []string{fn.display()},
+				Err:   fmt.Errorf("invalid value %q for flag %s: expect %s", value, fn.display(), joinNames(fn.enum, "or")),
+			}
+		}
+		for _, validate := range fn.validators {
+			if err := validate(); err != nil {
+				return &ArgumentError{
+					Kind:  ErrorInvalid,
+					Flags: []string{fn.display()},
+					Err:   fmt.Errorf("invalid value %q for flag %s: %w", value, fn.display(), err),
+				}
+			}
+		}
+	}
+
+	for _, fn := range f.names {
+		if fn.required && fn.source == SourceDefault {
+			return &ArgumentError{
+				Kind:  ErrorRequired,
+				Flags: []string{fn.display()},
+				Err:   fmt.Errorf("flag is required: %s", fn.display()),
+			}
+		}
+	}
+
+	for _, names := range f.exclusives {
+		var given []string
+		for _, name := range names {
+			if f.givenFlag(name) {
+				given = append(given, f.lookupName(name).display())
+			}
+		}
+		if len(given) > 1 {
+			return &ArgumentError{
+				Kind:  ErrorExclusive,
+				Flags: given,
+				Err:   fmt.Errorf("%s cannot be used together", joinNames(given, "and")),
+			}
+		}
+	}
+
+	for _, r := range f.requirements {
+		if !f.givenFlag(r.name) || slices.ContainsFunc(r.others, f.given) {
+			continue
+		}
+		name := f.lookupName(r.name).display()
+		var others []string
+		for _, other := range r.others {
+			others = append(others, f.lookupName(other).display())
+		}
+		return &ArgumentError{
+			Kind:  ErrorRequires,
+			Flags: append([]string{name}, others...),
+			Err:   fmt.Errorf("%s requires %s", name, joinNames(others, "or")),
+		}
+	}
+	return nil
+}
+
+// joinNames joins the names as a list in a sentence, e.g. "a, b or c".
+func joinNames(names []string, conjunction string) string {
+	if len(names) <= 1 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " " + conjunction + " " + names[len(names)-1]
+}
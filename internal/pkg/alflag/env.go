@@ -0,0 +1,81 @@
+package alflag
+
+import (
+	"os"
+	"strings"
+)
+
+// ValueSource is where the value of a flag comes from, see [FlagSet.Source].
+type ValueSource int
+
+const (
+	SourceDefault ValueSource = iota
+	SourceEnv
+	SourceFlag
+)
+
+func (s ValueSource) String() string {
+	switch s {
+	case SourceEnv:
+		return "env"
+	case SourceFlag:
+		return "flag"
+	}
+	return "default"
+}
+
+// SetEnvPrefix sets the prefix of the environment variables of the flags registered after it, e.g. the flag
+// "--dry-run" is bound to "EMIT_DEGIT_DRY_RUN" with the prefix "EMIT_DEGIT". The flags without a long name and
+// the flags registered before any prefix are not bound, unless [FlagSet.BindEnv] is used.
+func (f *FlagSet) SetEnvPrefix(prefix string) {
+	f.envPrefix = prefix
+}
+
+// BindEnv binds more environment variables to the registered flag, they are looked up in order after the
+// variable derived from the prefix, see [FlagSet.SetEnvPrefix].
+func (f *FlagSet) BindEnv(name string, envs ...string) {
+	fn := f.mustLookupName(name)
+	fn.envs = append(fn.envs, envs...)
+}
+
+// Source returns where the value of the flag comes from, and the flag as given in the arguments, e.g. "-v",
+// or the name of the environment variable.
+func (f *FlagSet) Source(name string) (ValueSource, string) {
+	fn := f.lookupName(name)
+	if fn == nil {
+		return SourceDefault, ""
+	}
+	return fn.source, fn.origin
+}
+
+// envName derives the name of the environment variable from the long name of the flag.
+func envName(prefix string, long string) string {
+	if prefix == "" || long == "" {
+		return ""
+	}
+	return prefix + "_" + strings.ToUpper(strings.ReplaceAll(long, "-", "_"))
+}
+
+// parseEnv sets the flags which are not given in the arguments from their environment variables, the first
+// non-empty variable is used. The flags excluded by a flag given in the arguments are not set, see
+// [FlagSet.MarkExclusive].
+func (f *FlagSet) parseEnv() error {
+	for i := range f.names {
+		fn := &f.names[i]
+		if fn.source == SourceFlag || f.excluded(fn) {
+			continue
+		}
+		for _, env := range fn.envs {
+			value, ok := os.LookupEnv(env)
+			if !ok || value == "" {
+				continue
+			}
+			if err := f.flagset.Set(fn.name(), value); err != nil {
+				return syntaxError(fn.display(), "invalid value %q for environment variable %s: %v", value, env, err)
+			}
+			fn.source, fn.origin = SourceEnv, env
+			break
+		}
+	}
+	return nil
+}
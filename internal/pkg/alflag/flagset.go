@@ -2,6 +2,7 @@ package alflag
 
 import (
 	"encoding"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -19,8 +20,31 @@ var (
 //   - [flag.FlagSet.Output] is set to [io.Discard].
 //   - [flag.FlagSet.Usage] is set to an empty function.
 //   - [flag.FlagSet.ErrorHandling] is set to [flag.ContinueOnError].
+//
+// Unlike [flag.FlagSet], the flags and the positional arguments can be interspersed by default,
+// see [FlagSet.SetInterspersed].
 type FlagSet struct {
 	flagset *flag.FlagSet
+
+	interspersed bool
+
+	// shorts and longs are the registered short and long names
+	shorts map[string]struct{}
+	longs  map[string]struct{}
+
+	// names are the registered flags in order for the help text, group is the group of the next flag
+	names []flagName
+	group string
+
+	// envPrefix is the prefix of the environment variables of the next flag
+	envPrefix string
+
+	// parent is the flag set of the parent command, its persistent flags are inherited
+	parent *FlagSet
+
+	// exclusives are the groups of mutually exclusive flags, requirements are the flags requiring others
+	exclusives   [][]string
+	requirements []requirement
 }
 
 func NewFlagSet(name string) *FlagSet {
@@ -28,7 +52,12 @@ func NewFlagSet(name string) *FlagSet {
 	flagset.SetOutput(io.Discard)
 	flagset.Usage = func() {}
 
-	return &FlagSet{flagset}
+	return &FlagSet{
+		flagset:      flagset,
+		interspersed: true,
+		shorts:       make(map[string]struct{}),
+		longs:        make(map[string]struct{}),
+	}
 }
 
 func (f *FlagSet) Arg(i int) string {
@@ -244,8 +273,181 @@ func (f *FlagSet) Name() string {
 	return f.flagset.Name()
 }
 
+// Parse parses the flags from the arguments, which must not include the command name. The flags follow the POSIX
+// and GNU conventions:
+//   - A short flag is given with one dash, the booleans can be bundled, e.g. "-vq", and a value can be attached,
+//     e.g. "-i~/.ssh/key", "-i=~/.ssh/key", or given as the next argument.
+//   - A long flag is given with two dashes, the value is given as "--output=file" or "--output file".
+//
+// The flags may follow the positional arguments, e.g. "repo dir --dry-run", unless the interspersed parsing is
+// disabled. The arguments after the terminator "--" are always positional.
+//
+// The flags which are not given are set from their environment variables, see [FlagSet.SetEnvPrefix]. Then the
+// constraints of the flags are checked, e.g. [FlagSet.MarkRequired]. Both are skipped if the "help" flag is
+// given, so the help text can always be printed. The errors are [ArgumentError].
 func (f *FlagSet) Parse(arguments []string) error {
-	return f.flagset.Parse(arguments)
+	var positionals []string
+	for i := 0; i < len(arguments); i++ {
+		arg := arguments[i]
+		if arg == "--" {
+			positionals = append(positionals, arguments[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			if !f.interspersed {
+				positionals = append(positionals, arguments[i:]...)
+				break
+			}
+			positionals = append(positionals, arg)
+			continue
+		}
+
+		var err error
+		if strings.HasPrefix(arg, "--") {
+			i, err = f.parseLong(arguments, i)
+		} else {
+			i, err = f.parseShort(arguments, i)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// The environment variables fill the flags which are not given
+	if !f.helpRequested() {
+		if err := f.parseEnv(); err != nil {
+			return err
+		}
+		if err := f.validate(); err != nil {
+			return err
+		}
+	}
+
+	// The flag.FlagSet records the positional arguments and the parsed state
+	return f.flagset.Parse(append([]string{"--"}, positionals...))
+}
+
+// parseLong parses the long flag at arguments[i], and returns the index of its last argument.
+func (f *FlagSet) parseLong(arguments []string, i int) (int, error) {
+	name, value, hasValue := strings.Cut(arguments[i][2:], "=")
+	owner := f.owner(name, true)
+	if owner == nil {
+		if f.owner(name, false) != nil {
+			return i, syntaxError("-"+name, "short flag must be given with one dash: -%s", name)
+		}
+		return i, f.unknownFlag("--"+name, name)
+	}
+
+	fl := owner.flagset.Lookup(name)
+	if !hasValue {
+		if isBoolFlag(fl) {
+			value = "true"
+		} else if i+1 < len(arguments) {
+			i++
+			value = arguments[i]
+		} else {
+			return i, syntaxError("--"+name, "flag needs an argument: --%s", name)
+		}
+	}
+	return i, owner.set(fl, "--"+name, value)
+}
+
+// parseShort parses the short flags bundled at arguments[i], and returns the index of its last argument.
+func (f *FlagSet) parseShort(arguments []string, i int) (int, error) {
+	bundle := arguments[i][1:]
+	if name, _, _ := strings.Cut(bundle, "="); len(name) > 1 {
+		if f.owner(name, true) != nil {
+			return i, syntaxError("--"+name, "long flag must be given with two dashes: --%s", name)
+		}
+	}
+
+	for j := 0; j < len(bundle); j++ {
+		name := bundle[j : j+1]
+		owner := f.owner(name, false)
+		if owner == nil {
+			// A long flag given with one dash is suggested by its name, e.g. "-dryrun"
+			word := ""
+			if j == 0 {
+				word, _, _ = strings.Cut(bundle, "=")
+			}
+			return i, f.unknownFlag("-"+name, word)
+		}
+		fl := owner.flagset.Lookup(name)
+		rest := bundle[j+1:]
+
+		if isBoolFlag(fl) {
+			// A boolean takes a value only with "=", e.g. "-v=false", otherwise the next flag is bundled
+			if value, ok := strings.CutPrefix(rest, "="); ok {
+				return i, owner.set(fl, "-"+name, value)
+			}
+			if err := owner.set(fl, "-"+name, "true"); err != nil {
+				return i, err
+			}
+			continue
+		}
+
+		// The rest of the bundle is the attached value, e.g. "-i~/.ssh/key" or "-i=~/.ssh/key"
+		if len(rest) != 0 {
+			return i, owner.set(fl, "-"+name, strings.TrimPrefix(rest, "="))
+		}
+		if i+1 >= len(arguments) {
+			return i, syntaxError("-"+name, "flag needs an argument: -%s", name)
+		}
+		return i + 1, owner.set(fl, "-"+name, arguments[i+1])
+	}
+	return i, nil
+}
+
+func (f *FlagSet) set(fl *flag.Flag, display string, value string) error {
+	if err := f.flagset.Set(fl.Name, value); err != nil {
+		return syntaxError(display, "invalid value %q for flag %s: %v", value, display, err)
+	}
+	if fn := f.lookupName(fl.Name); fn != nil {
+		fn.source, fn.origin = SourceFlag, display
+	}
+	return nil
+}
+
+// syntaxError returns an [ArgumentError] of the flag as shown in the arguments, e.g. "--output".
+func syntaxError(display string, format string, a ...any) error {
+	return &ArgumentError{Kind: ErrorSyntax, Flags: []string{display}, Err: fmt.Errorf(format, a...)}
+}
+
+// unknownFlag returns the error of a flag which is not defined, the long flags similar to the word are suggested.
+func (f *FlagSet) unknownFlag(display string, word string) error {
+	message := "unknown flag " + display
+	if f.Name() != "" {
+		message += " for the " + f.Name() + " command"
+	}
+
+	var longs []string
+	for _, fl := range append(f.Flags(), f.InheritedFlags()...) {
+		if fl.Long != "" {
+			longs = append(longs, fl.Long)
+		}
+	}
+	var suggestions []string
+	if len(word) > 1 {
+		for _, long := range Suggest(word, longs) {
+			suggestions = append(suggestions, "--"+long)
+		}
+	}
+	if len(suggestions) != 0 {
+		message += ", did you mean " + joinNames(suggestions, "or") + "?"
+	}
+	return &ArgumentError{Kind: ErrorSyntax, Flags: []string{display}, Suggestions: suggestions, Err: errors.New(message)}
+}
+
+func isBoolFlag(fl *flag.Flag) bool {
+	bv, ok := fl.Value.(interface{ IsBoolFlag() bool })
+	return ok && bv.IsBoolFlag()
+}
+
+// SetInterspersed sets whether the flags may follow the positional arguments, it is enabled by default.
+// A command dispatching to subcommands disables it, so that the flags after the subcommand name are left for
+// the subcommand.
+func (f *FlagSet) SetInterspersed(interspersed bool) {
+	f.interspersed = interspersed
 }
 
 func (f *FlagSet) Parsed() bool {
@@ -290,6 +492,54 @@ func (f *FlagSet) StringVar(p *string, name string, value string, usage ...strin
 	}
 }
 
+// StringMap defines a repeatable flag of "key=value" pairs, e.g. "--var a=1 --var b=2".
+// The given pairs replace the default value.
+func (f *FlagSet) StringMap(name string, value map[string]string, usage ...string) *map[string]string {
+	ptr := new(map[string]string)
+	f.StringMapVar(ptr, name, value, usage...)
+	return ptr
+}
+
+func (f *FlagSet) StringMapVar(p *map[string]string, name string, value map[string]string, usage ...string) {
+	f.Var(newStringMapValue(p, value, false), name, usage...)
+}
+
+// StringMapComma works like [FlagSet.StringMap] but each occurrence may have comma-separated pairs,
+// e.g. "--var a=1,b=2".
+func (f *FlagSet) StringMapComma(name string, value map[string]string, usage ...string) *map[string]string {
+	ptr := new(map[string]string)
+	f.StringMapCommaVar(ptr, name, value, usage...)
+	return ptr
+}
+
+func (f *FlagSet) StringMapCommaVar(p *map[string]string, name string, value map[string]string, usage ...string) {
+	f.Var(newStringMapValue(p, value, true), name, usage...)
+}
+
+// StringSlice defines a repeatable flag collecting every occurrence, e.g. "--exclude x --exclude y".
+// The given values replace the default value.
+func (f *FlagSet) StringSlice(name string, value []string, usage ...string) *[]string {
+	ptr := new([]string)
+	f.StringSliceVar(ptr, name, value, usage...)
+	return ptr
+}
+
+func (f *FlagSet) StringSliceVar(p *[]string, name string, value []string, usage ...string) {
+	f.Var(newStringSliceValue(p, value, false), name, usage...)
+}
+
+// StringSliceComma works like [FlagSet.StringSlice] but each occurrence may have comma-separated values,
+// e.g. "--exclude x,y". The empty values are skipped.
+func (f *FlagSet) StringSliceComma(name string, value []string, usage ...string) *[]string {
+	ptr := new([]string)
+	f.StringSliceCommaVar(ptr, name, value, usage...)
+	return ptr
+}
+
+func (f *FlagSet) StringSliceCommaVar(p *[]string, name string, value []string, usage ...string) {
+	f.Var(newStringSliceValue(p, value, true), name, usage...)
+}
+
 func (f *FlagSet) TextVar(p encoding.TextUnmarshaler, name string, value encoding.TextMarshaler, usage ...string) {
 	usageValue := ""
 	if len(usage) > 0 {
@@ -387,7 +637,34 @@ func (f *FlagSet) Var(value Value, name string, usage ...string) {
 	}
 }
 
+// parseName splits the name into its short and long names, and records them for the parsing and the help text.
 func (f *FlagSet) parseName(name string) (string, string) {
+	short, long := splitName(name)
+	if short != "" {
+		f.shorts[short] = struct{}{}
+	}
+	if long != "" {
+		f.longs[long] = struct{}{}
+	}
+	fn := flagName{short: short, long: long, group: f.group}
+	if env := envName(f.envPrefix, long); env != "" {
+		fn.envs = append(fn.envs, env)
+	}
+	f.names = append(f.names, fn)
+	return short, long
+}
+
+// lookupName returns the registered flag with the short or long name, nil is returned if it is not registered.
+func (f *FlagSet) lookupName(name string) *flagName {
+	for i := range f.names {
+		if f.names[i].short == name || f.names[i].long == name {
+			return &f.names[i]
+		}
+	}
+	return nil
+}
+
+func splitName(name string) (string, string) {
 	parts := strings.SplitN(name, ",", 2)
 	short := ""
 	long := ""
@@ -1,6 +1,7 @@
 package alflag
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
@@ -100,9 +101,9 @@ func TestFlagSet__setup(t *testing.T) {
 			},
 			cases: [][]string{
 				{"-d=0s"},
-				{"-d2=0s"},
-				{"-d2=0"},
-				{"-d=1s", "-d2=1s"},
+				{"--d2=0s"},
+				{"--d2=0"},
+				{"-d=1s", "--d2=1s"},
 				{"--duration=1s", "--d2=1s"},
 			},
 			wants: []map[string]any{
@@ -126,8 +127,8 @@ func TestFlagSet__setup(t *testing.T) {
 			},
 			cases: [][]string{
 				{"-f=0"},
-				{"-f2=0"},
-				{"-f=1.0", "-f2=1.0"},
+				{"--f2=0"},
+				{"-f=1.0", "--f2=1.0"},
 				{"--float=1.0", "--f2=1.0"},
 			},
 			wants: []map[string]any{
@@ -150,8 +151,8 @@ func TestFlagSet__setup(t *testing.T) {
 			},
 			cases: [][]string{
 				{"-i=0"},
-				{"-i2=0"},
-				{"-i=1", "-i2=1"},
+				{"--i2=0"},
+				{"-i=1", "--i2=1"},
 				{"--int=1", "--i2=2"},
 			},
 			wants: []map[string]any{
@@ -174,8 +175,8 @@ func TestFlagSet__setup(t *testing.T) {
 			},
 			cases: [][]string{
 				{"-i=0"},
-				{"-i642=0"},
-				{"-i=1", "-i642=1"},
+				{"--i642=0"},
+				{"-i=1", "--i642=1"},
 				{"--int64=1", "--i642=1"},
 			},
 			wants: []map[string]any{
@@ -198,8 +199,8 @@ func TestFlagSet__setup(t *testing.T) {
 			},
 			cases: [][]string{
 				{"-s="},
-				{"-s2="},
-				{"-s=test", "-s2=test"},
+				{"--s2="},
+				{"-s=test", "--s2=test"},
 				{"--string=test", "--s2=test"},
 			},
 			wants: []map[string]any{
@@ -222,8 +223,8 @@ func TestFlagSet__setup(t *testing.T) {
 			},
 			cases: [][]string{
 				{"-u=0"},
-				{"-u2=0"},
-				{"-u=1", "-u2=1"},
+				{"--u2=0"},
+				{"-u=1", "--u2=1"},
 				{"--uint=1", "--u2=1"},
 			},
 			wants: []map[string]any{
@@ -246,8 +247,8 @@ func TestFlagSet__setup(t *testing.T) {
 			},
 			cases: [][]string{
 				{"-u=0"},
-				{"-u642=0"},
-				{"-u=1", "-u642=1"},
+				{"--u642=0"},
+				{"-u=1", "--u642=1"},
 				{"--uint64=1", "--u642=22"},
 			},
 			wants: []map[string]any{
@@ -315,3 +316,539 @@ func TestFlagSet__setup(t *testing.T) {
 	}
 
 }
+
+func TestFlagSet_Parse_interspersed(t *testing.T) {
+	type testcase struct {
+		args         []string
+		interspersed bool
+		verbose      bool
+		output       string
+		positionals  []string
+	}
+
+	tests := []testcase{
+		{[]string{"repo", "dir", "--verbose"}, true, true, "", []string{"repo", "dir"}},
+		{[]string{"repo", "-o", "out", "dir"}, true, false, "out", []string{"repo", "dir"}},
+		{[]string{"-v", "repo", "--output=out"}, true, true, "out", []string{"repo"}},
+		{[]string{"repo", "--", "-v", "--output=out"}, true, false, "", []string{"repo", "-v", "--output=out"}},
+		{[]string{"-o", "--", "repo"}, true, false, "--", []string{"repo"}},
+		{[]string{"-", "-v"}, true, true, "", []string{"-"}},
+		{[]string{"-v", "repo", "--output=out"}, false, true, "", []string{"repo", "--output=out"}},
+		{[]string{"--", "repo", "-v"}, false, false, "", []string{"repo", "-v"}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%q interspersed=%v", test.args, test.interspersed), func(t *testing.T) {
+			fs := NewFlagSet("test")
+			fs.SetInterspersed(test.interspersed)
+			verbose := fs.Bool("v, verbose", false)
+			output := fs.String("o, output", "")
+
+			if err := fs.Parse(test.args); err != nil {
+				t.Fatalf("Parse(%q) error: %v", test.args, err)
+			}
+			if *verbose != test.verbose {
+				t.Errorf("verbose = %v; want %v", *verbose, test.verbose)
+			}
+			if *output != test.output {
+				t.Errorf("output = %q; want %q", *output, test.output)
+			}
+			if !reflect.DeepEqual(fs.Args(), test.positionals) && (len(fs.Args()) != 0 || len(test.positionals) != 0) {
+				t.Errorf("Args() = %q; want %q", fs.Args(), test.positionals)
+			}
+		})
+	}
+}
+
+func TestFlagSet_Parse_interspersedUnknown(t *testing.T) {
+	fs := NewFlagSet("test")
+	fs.Bool("v, verbose", false)
+
+	if err := fs.Parse([]string{"repo", "--unknown"}); err == nil {
+		t.Errorf("Parse() of an unknown flag after a positional should fail")
+	}
+}
+
+func TestFlagSet_Parse_posix(t *testing.T) {
+	type testcase struct {
+		args     []string
+		verbose  bool
+		dryRun   bool
+		identity string
+		output   string
+		wantErr  bool
+	}
+
+	tests := []testcase{
+		{args: []string{"-vn"}, verbose: true, dryRun: true},
+		{args: []string{"-v", "-n"}, verbose: true, dryRun: true},
+		{args: []string{"-i~/.ssh/key"}, identity: "~/.ssh/key"},
+		{args: []string{"-i=~/.ssh/key"}, identity: "~/.ssh/key"},
+		{args: []string{"-i", "~/.ssh/key"}, identity: "~/.ssh/key"},
+		{args: []string{"-vni", "key"}, verbose: true, dryRun: true, identity: "key"},
+		{args: []string{"-vikey"}, verbose: true, identity: "key"},
+		{args: []string{"-v=false", "-n"}, dryRun: true},
+		{args: []string{"--verbose", "--dry-run=true"}, verbose: true, dryRun: true},
+		{args: []string{"--output=file"}, output: "file"},
+		{args: []string{"--output", "file"}, output: "file"},
+		{args: []string{"--output", "-v"}, output: "-v"},
+
+		{args: []string{"-verbose"}, wantErr: true},
+		{args: []string{"-output=file"}, wantErr: true},
+		{args: []string{"--v"}, wantErr: true},
+		{args: []string{"--i=key"}, wantErr: true},
+		{args: []string{"-vx"}, wantErr: true},
+		{args: []string{"--unknown"}, wantErr: true},
+		{args: []string{"-i"}, wantErr: true},
+		{args: []string{"--output"}, wantErr: true},
+		{args: []string{"-v=maybe"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%q", test.args), func(t *testing.T) {
+			fs := NewFlagSet("test")
+			verbose := fs.Bool("v, verbose", false)
+			dryRun := fs.Bool("n, dry-run", false)
+			identity := fs.String("i", "")
+			output := fs.String("output", "")
+
+			err := fs.Parse(test.args)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Parse(%q) error = %v; wantErr %v", test.args, err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if *verbose != test.verbose || *dryRun != test.dryRun || *identity != test.identity || *output != test.output {
+				t.Errorf("Parse(%q) = verbose %v, dry-run %v, identity %q, output %q; want %v, %v, %q, %q",
+					test.args, *verbose, *dryRun, *identity, *output, test.verbose, test.dryRun, test.identity, test.output)
+			}
+		})
+	}
+}
+
+func TestFlagSet_FlagUsages(t *testing.T) {
+	fs := NewFlagSet("test")
+	fs.Bool("h, help", false, "Print this help message and exit")
+	fs.String("mode", "git", "How to fetch, the `mode` is git or tar\nThe \"tar\" mode is faster")
+	fs.String("i", "", "The `path` to the identity file")
+	fs.BindEnv("i", "TEST_IDENTITY")
+	fs.SetGroup("NETWORK OPTIONS")
+	fs.Duration("timeout", 0, "Abort the command if it takes longer than the `duration`")
+	fs.Int("retries", 0, "Retry the network operations on transient errors with exponential backoff, up to `count` times")
+	fs.String("submodule-url", "", "Rewrite the URLs, given as `<prefix>=<replacement>`")
+
+	want := `OPTIONS:
+    -h, --help                 Print this help message and exit
+    --mode <mode>              How to fetch, the mode is git or tar (default: git)
+                               The "tar" mode is faster
+    -i <path>                  The path to the identity file (env: TEST_IDENTITY)
+
+NETWORK OPTIONS:
+    --timeout <duration>       Abort the command if it takes longer than the duration
+    --retries <count>          Retry the network operations on transient errors with exponential
+                               backoff, up to count times
+    --submodule-url <prefix>=<replacement>
+                               Rewrite the URLs, given as <prefix>=<replacement>
+`
+	if got := fs.FlagUsages(); got != want {
+		t.Errorf("FlagUsages() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFlagSet_StringSlice(t *testing.T) {
+	type testcase struct {
+		args  []string
+		comma bool
+		want  []string
+	}
+
+	tests := []testcase{
+		{nil, false, []string{"default"}},
+		{[]string{"-x", "a"}, false, []string{"a"}},
+		{[]string{"-x", "a", "--exclude=b"}, false, []string{"a", "b"}},
+		{[]string{"-x", "a,b"}, false, []string{"a,b"}},
+		{[]string{"-x", "a,b", "-x", "c"}, true, []string{"a", "b", "c"}},
+		{[]string{"-x", "a,,b,"}, true, []string{"a", "b"}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%q comma=%v", test.args, test.comma), func(t *testing.T) {
+			fs := NewFlagSet("test")
+			var got *[]string
+			if test.comma {
+				got = fs.StringSliceComma("x, exclude", []string{"default"})
+			} else {
+				got = fs.StringSlice("x, exclude", []string{"default"})
+			}
+
+			if err := fs.Parse(test.args); err != nil {
+				t.Fatalf("Parse(%q) error: %v", test.args, err)
+			}
+			if !reflect.DeepEqual(*got, test.want) {
+				t.Errorf("Parse(%q) = %q; want %q", test.args, *got, test.want)
+			}
+		})
+	}
+}
+
+func TestFlagSet_StringMap(t *testing.T) {
+	type testcase struct {
+		args    []string
+		comma   bool
+		want    map[string]string
+		wantErr bool
+	}
+
+	tests := []testcase{
+		{nil, false, map[string]string{"default": "1"}, false},
+		{[]string{"--var", "a=1", "--var", "b=2"}, false, map[string]string{"a": "1", "b": "2"}, false},
+		{[]string{"--var", "a=1", "--var", "a=2"}, false, map[string]string{"a": "2"}, false},
+		{[]string{"--var", "a=1,b=2"}, false, map[string]string{"a": "1,b=2"}, false},
+		{[]string{"--var", "a=1,b=2", "--var=c="}, true, map[string]string{"a": "1", "b": "2", "c": ""}, false},
+		{[]string{"--var", "a"}, false, nil, true},
+		{[]string{"--var", "=1"}, false, nil, true},
+		{[]string{"--var", "a=1,b"}, true, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%q comma=%v", test.args, test.comma), func(t *testing.T) {
+			fs := NewFlagSet("test")
+			var got *map[string]string
+			if test.comma {
+				got = fs.StringMapComma("var", map[string]string{"default": "1"})
+			} else {
+				got = fs.StringMap("var", map[string]string{"default": "1"})
+			}
+
+			err := fs.Parse(test.args)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Parse(%q) error = %v; wantErr %v", test.args, err, test.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(*got, test.want) {
+				t.Errorf("Parse(%q) = %v; want %v", test.args, *got, test.want)
+			}
+		})
+	}
+}
+
+func TestFlagSet_listDefaults(t *testing.T) {
+	fs := NewFlagSet("test")
+	defaults := []string{"a", "b"}
+	fs.StringSlice("exclude", defaults, "Exclude the `pattern`")
+	fs.StringMap("var", map[string]string{"b": "2", "a": "1"}, "Set the `key=value`")
+
+	if err := fs.Parse([]string{"--exclude", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(defaults, []string{"a", "b"}) {
+		t.Errorf("the default slice is modified: %q", defaults)
+	}
+
+	flags := fs.Flags()
+	if flags[0].Default != "a,b" || flags[1].Default != "a=1,b=2" {
+		t.Errorf("Flags() defaults = %q, %q; want %q, %q", flags[0].Default, flags[1].Default, "a,b", "a=1,b=2")
+	}
+}
+
+func TestFlagSet_env(t *testing.T) {
+	type testcase struct {
+		name    string
+		args    []string
+		env     map[string]string
+		output  string
+		verbose bool
+		source  ValueSource
+		origin  string
+		wantErr bool
+	}
+
+	tests := []testcase{
+		{"default", nil, nil, "dist", false, SourceDefault, "", false},
+		{"prefix", nil, map[string]string{"TEST_OUTPUT_DIR": "env"}, "env", false, SourceEnv, "TEST_OUTPUT_DIR", false},
+		{"flag", []string{"-o", "flag"}, map[string]string{"TEST_OUTPUT_DIR": "env"}, "flag", false, SourceFlag, "-o", false},
+		{"bound", nil, map[string]string{"OUTPUT": "bound"}, "bound", false, SourceEnv, "OUTPUT", false},
+		{"order", nil, map[string]string{"TEST_OUTPUT_DIR": "env", "OUTPUT": "bound"}, "env", false, SourceEnv, "TEST_OUTPUT_DIR", false},
+		{"empty", nil, map[string]string{"TEST_OUTPUT_DIR": "", "OUTPUT": "bound"}, "bound", false, SourceEnv, "OUTPUT", false},
+		{"bool", nil, map[string]string{"VERBOSE": "1"}, "dist", true, SourceDefault, "", false},
+		{"invalid", nil, map[string]string{"VERBOSE": "yes"}, "dist", false, SourceDefault, "", true},
+		{"unprefixed", nil, map[string]string{"TEST_HELP": "1"}, "dist", false, SourceDefault, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+
+			fs := NewFlagSet("test")
+			help := fs.Bool("h, help", false, "")
+			fs.SetEnvPrefix("TEST")
+			output := fs.String("o, output-dir", "dist", "")
+			verbose := fs.Bool("v", false, "")
+			fs.BindEnv("o", "OUTPUT")
+			fs.BindEnv("v", "VERBOSE")
+
+			err := fs.Parse(test.args)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Parse(%q) error = %v; want error %v", test.args, err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if *output != test.output || *verbose != test.verbose || *help {
+				t.Errorf("Parse(%q) = output %q, verbose %v, help %v; want %q, %v, false",
+					test.args, *output, *verbose, *help, test.output, test.verbose)
+			}
+			if source, origin := fs.Source("output-dir"); source != test.source || origin != test.origin {
+				t.Errorf("Source(%q) = %v, %q; want %v, %q", "output-dir", source, origin, test.source, test.origin)
+			}
+		})
+	}
+}
+
+func TestFlagSet_constraints(t *testing.T) {
+	type testcase struct {
+		args  []string
+		kind  ErrorKind
+		flags []string
+		err   string
+	}
+
+	tests := []testcase{
+		{[]string{"-o", "dist"}, 0, nil, ""},
+		{[]string{"-o", "dist", "--mode", "tar", "-l", "me", "-p", "secret", "--retries", "1"}, 0, nil, ""},
+		{nil, ErrorRequired, []string{"--output"}, "flag is required: --output"},
+		{[]string{"-o", "dist", "--mode", "zip"}, ErrorEnum, []string{"--mode"}, `invalid value "zip" for flag --mode: expect git or tar`},
+		{[]string{"-o", "dist", "-p", "secret", "--no-secrets"}, ErrorExclusive, []string{"--no-secrets", "-p"}, "--no-secrets and -p cannot be used together"},
+		{[]string{"-o", "dist", "-p", "secret"}, ErrorRequires, []string{"-p", "-l", "-i"}, "-p requires -l or -i"},
+		{[]string{"-o", "dist", "--retries=-1"}, ErrorInvalid, []string{"--retries"}, `invalid value "-1" for flag --retries: must not be negative`},
+		{[]string{"-o", "dist", "--unknown"}, ErrorSyntax, []string{"--unknown"}, "unknown flag --unknown for the test command"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.args), func(t *testing.T) {
+			fs := NewFlagSet("test")
+			fs.String("o, output", "", "")
+			fs.String("mode", "git", "")
+			fs.String("i", "", "")
+			fs.String("l", "", "")
+			fs.String("p", "", "")
+			fs.Bool("no-secrets", false, "")
+			retries := fs.Int("retries", 0, "")
+
+			fs.MarkRequired("output")
+			fs.SetEnum("mode", "git", "tar")
+			fs.MarkExclusive("no-secrets", "p")
+			fs.MarkRequires("p", "l", "i")
+			fs.AddValidator("retries", func() error {
+				if *retries < 0 {
+					return fmt.Errorf("must not be negative")
+				}
+				return nil
+			})
+
+			err := fs.Parse(test.args)
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("Parse(%q) error = %v; want nil", test.args, err)
+				}
+				return
+			}
+
+			var argErr *ArgumentError
+			if !errors.As(err, &argErr) {
+				t.Fatalf("Parse(%q) error = %v; want an *ArgumentError", test.args, err)
+			}
+			if argErr.Kind != test.kind || !reflect.DeepEqual(argErr.Flags, test.flags) || argErr.Error() != test.err {
+				t.Errorf("Parse(%q) error = %d %q %q; want %d %q %q",
+					test.args, argErr.Kind, argErr.Flags, argErr.Error(), test.kind, test.flags, test.err)
+			}
+		})
+	}
+}
+
+func TestFlagSet_constraintsEnv(t *testing.T) {
+	type testcase struct {
+		name    string
+		args    []string
+		env     map[string]string
+		output  string
+		tar     string
+		wantErr string
+	}
+
+	tests := []testcase{
+		{"requires", nil, map[string]string{"SECRETS": "x"}, "", "", ""},
+		{"requires satisfied", []string{"-p", "x"}, map[string]string{"USERNAME": "me"}, "", "", ""},
+		{"requires flag", []string{"-p", "x"}, nil, "", "", "-p requires -l"},
+		{"exclusive flag wins", []string{"-o", "x.zip"}, map[string]string{"TEST_TAR": "-"}, "x.zip", "", ""},
+		{"exclusive env", nil, map[string]string{"TEST_OUTPUT": "x.zip", "TEST_TAR": "-"}, "x.zip", "-", ""},
+		{"exclusive flags", []string{"-o", "x.zip", "--tar", "-"}, nil, "", "", "--output and --tar cannot be used together"},
+		{"enum", nil, map[string]string{"TEST_PROGRESS": "foo"}, "", "", `invalid value "foo" for flag --progress: expect bar or none`},
+		{"help", []string{"-h"}, map[string]string{"TEST_PROGRESS": "foo", "VERBOSE": "yes"}, "", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+
+			fs := NewFlagSet("test")
+			fs.Bool("h, help", false, "")
+			fs.SetEnvPrefix("TEST")
+			fs.Bool("v", false, "")
+			fs.String("progress", "bar", "")
+			output := fs.String("o, output", "", "")
+			tar := fs.String("tar", "", "")
+			fs.String("l", "", "")
+			fs.String("p", "", "")
+			fs.BindEnv("v", "VERBOSE")
+			fs.BindEnv("l", "USERNAME")
+			fs.BindEnv("p", "SECRETS")
+
+			fs.SetEnum("progress", "bar", "none")
+			fs.MarkExclusive("output", "tar")
+			fs.MarkRequires("p", "l")
+
+			err := fs.Parse(test.args)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("Parse(%q) error = %v; want %q", test.args, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v; want nil", test.args, err)
+			}
+			if *output != test.output || *tar != test.tar {
+				t.Errorf("Parse(%q) = output %q, tar %q; want %q, %q", test.args, *output, *tar, test.output, test.tar)
+			}
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"version", "degit", "refs", "update", "diff"}
+	tests := map[string][]string{
+		"degti":  {"degit"},
+		"dif":    {"diff"},
+		"upd":    {"update"},
+		"ref":    {"refs"},
+		"clone":  nil,
+		"degit":  nil,
+		"diffs":  {"diff"},
+		"versio": {"version"},
+	}
+	for name, want := range tests {
+		if got := Suggest(name, candidates); !reflect.DeepEqual(got, want) {
+			t.Errorf("Suggest(%q) = %q; want %q", name, got, want)
+		}
+	}
+}
+
+func TestFlagSet_Parse_unknownSuggestions(t *testing.T) {
+	tests := map[string]string{
+		"--dryrun":  "unknown flag --dryrun for the degit command, did you mean --dry-run?",
+		"--verbos":  "unknown flag --verbos for the degit command, did you mean --verbose?",
+		"-dryrun":   "unknown flag -d for the degit command, did you mean --dry-run?",
+		"--unknown": "unknown flag --unknown for the degit command",
+		"-x":        "unknown flag -x for the degit command",
+	}
+	for arg, want := range tests {
+		fs := NewFlagSet("degit")
+		fs.Bool("dry-run", false, "")
+		fs.Bool("v, verbose", false, "")
+		if err := fs.Parse([]string{arg}); err == nil || err.Error() != want {
+			t.Errorf("Parse(%q) error = %v; want %q", arg, err, want)
+		}
+	}
+}
+
+func TestFlagSet_persistent(t *testing.T) {
+	root := NewFlagSet("emit")
+	root.SetInterspersed(false)
+	verbose := root.Bool("v, verbose", false, "Enable verbose output")
+	config := root.String("config", "", "The `path` of the config file")
+	root.Bool("local", false, "Not inherited")
+	root.MarkPersistent("verbose", "config")
+
+	group := NewFlagSet("cache")
+	group.SetParent(root)
+	group.SetInterspersed(false)
+	dir := group.String("dir", "", "The cache `directory`")
+	group.MarkPersistent("dir")
+
+	cmd := NewFlagSet("list")
+	cmd.SetParent(group)
+	all := cmd.Bool("a, all", false, "List all")
+	cmdConfig := cmd.String("config", "", "Shadows the config of emit")
+
+	if err := cmd.Parse([]string{"-va", "--dir", "/tmp", "--config=x", "arg"}); err != nil {
+		t.Fatal(err)
+	}
+	if !*verbose || !*all || *dir != "/tmp" || *cmdConfig != "x" || *config != "" || cmd.Arg(0) != "arg" {
+		t.Errorf("Parse() = verbose %v, all %v, dir %q, config %q %q, arg %q",
+			*verbose, *all, *dir, *cmdConfig, *config, cmd.Arg(0))
+	}
+	if source, origin := root.Source("verbose"); source != SourceFlag || origin != "-v" {
+		t.Errorf("Source(%q) = %v, %q; want flag, -v", "verbose", source, origin)
+	}
+
+	if err := cmd.Parse([]string{"--local"}); err == nil {
+		t.Errorf("Parse(--local) should fail for a flag which is not persistent")
+	}
+
+	want := `OPTIONS:
+    -a, --all                  List all
+    --config <string>          Shadows the config of emit
+
+GLOBAL OPTIONS:
+    --dir <directory>          The cache directory
+    -v, --verbose              Enable verbose output
+`
+	if got := cmd.FlagUsages(); got != want {
+		t.Errorf("FlagUsages() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFlagSet_persistentShadowed(t *testing.T) {
+	root := NewFlagSet("emit")
+	root.SetInterspersed(false)
+	verbose := root.Bool("v, verbose", false, "Enable verbose output")
+	rootConfig := root.String("config", "", "The config of emit")
+	root.MarkPersistent("verbose", "config")
+
+	// The group shadows the persistent --config of the root with its own persistent flag
+	group := NewFlagSet("cache")
+	group.SetParent(root)
+	group.SetInterspersed(false)
+	groupConfig := group.String("c, config", "", "The config of the cache")
+	group.MarkPersistent("config")
+
+	// The subcommand shadows the short name of the persistent -v of the root
+	cmd := NewFlagSet("list")
+	cmd.SetParent(group)
+	version := cmd.Bool("v", false, "Print the version")
+
+	if err := cmd.Parse([]string{"-v", "--config=x", "--verbose"}); err != nil {
+		t.Fatal(err)
+	}
+	if !*version || !*verbose || *groupConfig != "x" || *rootConfig != "" {
+		t.Errorf("Parse() = version %v, verbose %v, config %q %q; want true, true, x, empty",
+			*version, *verbose, *groupConfig, *rootConfig)
+	}
+
+	var inherited []string
+	for _, fl := range cmd.InheritedFlags() {
+		inherited = append(inherited, fl.Name())
+	}
+	want := []string{"-c, --config <string>"}
+	if !reflect.DeepEqual(inherited, want) {
+		t.Errorf("InheritedFlags() = %q; want %q", inherited, want)
+	}
+	if got := group.InheritedFlags(); len(got) != 1 || got[0].Long != "verbose" {
+		t.Errorf("InheritedFlags() of the group = %v; want --verbose only", got)
+	}
+}
@@ -0,0 +1,246 @@
+package alflag
+
+import (
+	"flag"
+	"strings"
+)
+
+const (
+	// HelpWidth is the width which the help text is wrapped at
+	HelpWidth = 100
+	// HelpIndent is the indentation of the flags in the help text
+	HelpIndent = 4
+	// HelpColumn is the column where the usages of the flags start, a longer flag name is followed by
+	// its usage on the next line
+	HelpColumn = 31
+)
+
+// globalGroup is the title of the section of the inherited flags in the help text.
+const globalGroup = "GLOBAL OPTIONS"
+
+// Flag is the metadata of a registered flag shown in the help text.
+type Flag struct {
+	Short string
+	Long  string
+	// Placeholder is the name of the value, e.g. "<path>", it is empty for booleans
+	Placeholder string
+	// Default is the default value, it is empty for a zero value
+	Default string
+	// Usage is the description of the flag without the backquotes of the placeholder
+	Usage string
+	// Group is the title of the group of the flag, see [FlagSet.SetGroup]
+	Group string
+	// Env is the environment variables bound to the flag in the lookup order, see [FlagSet.SetEnvPrefix]
+	Env []string
+	// Required reports whether the flag must be given, see [FlagSet.MarkRequired]
+	Required bool
+	// Enum is the choices of the value, see [FlagSet.SetEnum]
+	Enum []string
+	// Persistent reports whether the flag is inherited by the subcommands, see [FlagSet.MarkPersistent]
+	Persistent bool
+	// Filename reports whether the value is a file path, see [FlagSet.MarkFilename]
+	Filename bool
+}
+
+// Name returns the names of the flag with its placeholder as shown in the help text, e.g. "-o, --output <file>".
+func (f Flag) Name() string {
+	var names []string
+	if f.Short != "" {
+		names = append(names, "-"+f.Short)
+	}
+	if f.Long != "" {
+		names = append(names, "--"+f.Long)
+	}
+	name := strings.Join(names, ", ")
+	if f.Placeholder != "" {
+		name += " " + f.Placeholder
+	}
+	return name
+}
+
+// flagName is a flag as registered, the rest of its metadata is looked up from the flag.FlagSet.
+type flagName struct {
+	short, long string
+	group       string
+	envs        []string
+
+	// required, enum and validators are the constraints of the value, see [FlagSet.validate]
+	required   bool
+	enum       []string
+	validators []func() error
+	persistent bool
+	filename   bool
+
+	// source is where the value comes from, origin is the flag as given or the environment variable
+	source ValueSource
+	origin string
+}
+
+// name returns the name of the flag in the flag.FlagSet.
+func (n flagName) name() string {
+	if n.long != "" {
+		return n.long
+	}
+	return n.short
+}
+
+// display returns the flag as shown in the help text and the errors, e.g. "--output" or "-p".
+func (n flagName) display() string {
+	if n.long != "" {
+		return "--" + n.long
+	}
+	return "-" + n.short
+}
+
+// MarkFilename marks the values of the registered flags as file paths, which are completed by the shells.
+func (f *FlagSet) MarkFilename(names ...string) {
+	for _, name := range names {
+		f.mustLookupName(name).filename = true
+	}
+}
+
+// SetGroup sets the group of the flags registered after it, the flags are shown under the title of their group
+// in the help text. The flags registered before any group are shown under "OPTIONS".
+func (f *FlagSet) SetGroup(title string) {
+	f.group = title
+}
+
+// Flags returns the metadata of the registered flags in the registration order.
+func (f *FlagSet) Flags() []Flag {
+	var flags []Flag
+	for _, name := range f.names {
+		fl := f.flagset.Lookup(name.name())
+		if fl == nil {
+			continue
+		}
+
+		// The placeholder is the backquoted name of the usage, or the type of the value, e.g. "duration"
+		placeholder, usage := flag.UnquoteUsage(fl)
+		if placeholder != "" && !strings.Contains(placeholder, "<") {
+			placeholder = "<" + placeholder + ">"
+		}
+		flags = append(flags, Flag{
+			Short:       name.short,
+			Long:        name.long,
+			Placeholder: placeholder,
+			Default:     defaultValue(fl),
+			Usage:       usage,
+			Group:       name.group,
+			Env:         name.envs,
+			Required:    name.required,
+			Enum:        name.enum,
+			Persistent:  name.persistent,
+			Filename:    name.filename,
+		})
+	}
+	return flags
+}
+
+// defaultValue returns the default value of the flag, an empty string is returned for a zero value.
+func defaultValue(fl *flag.Flag) string {
+	switch fl.DefValue {
+	case "", "false", "0", "0s", "[]":
+		return ""
+	}
+	return fl.DefValue
+}
+
+// FlagUsages returns the help sections of the flags, a section for each group in the order of registration.
+// The inherited flags are shown in the last section "GLOBAL OPTIONS", see [FlagSet.SetParent].
+func (f *FlagSet) FlagUsages() string {
+	var titles []string
+	groups := make(map[string][]Flag)
+	for _, fl := range f.Flags() {
+		if _, ok := groups[fl.Group]; !ok {
+			titles = append(titles, fl.Group)
+		}
+		groups[fl.Group] = append(groups[fl.Group], fl)
+	}
+	if inherited := f.InheritedFlags(); len(inherited) != 0 {
+		titles = append(titles, globalGroup)
+		groups[globalGroup] = inherited
+	}
+
+	var sb strings.Builder
+	for i, title := range titles {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		heading := title
+		if heading == "" {
+			heading = "OPTIONS"
+		}
+		sb.WriteString(heading + ":\n")
+		for _, fl := range groups[title] {
+			// The notes of the value and the environment variables end the first line of the usage
+			var notes []string
+			if fl.Required {
+				notes = append(notes, "required")
+			}
+			if fl.Default != "" {
+				notes = append(notes, "default: "+fl.Default)
+			}
+			if len(fl.Env) != 0 {
+				notes = append(notes, "env: "+strings.Join(fl.Env, ", "))
+			}
+			usage := fl.Usage
+			if len(notes) != 0 {
+				first, rest, found := strings.Cut(usage, "\n")
+				usage = first + " (" + strings.Join(notes, "; ") + ")"
+				if found {
+					usage += "\n" + rest
+				}
+			}
+			sb.WriteString(FormatEntry(fl.Name(), usage))
+		}
+	}
+	return sb.String()
+}
+
+// FormatEntry formats a name and its description as a line of a help section, the description is aligned at
+// [HelpColumn] and wrapped at [HelpWidth]. Each line of the description starts a new paragraph.
+func FormatEntry(name string, description string) string {
+	var sb strings.Builder
+	indent := strings.Repeat(" ", HelpIndent)
+	sb.WriteString(indent + name)
+
+	column := HelpIndent + len(name)
+	if column+2 > HelpColumn {
+		sb.WriteString("\n")
+		column = 0
+	}
+	sb.WriteString(strings.Repeat(" ", HelpColumn-column))
+
+	for i, paragraph := range strings.Split(description, "\n") {
+		if i > 0 {
+			sb.WriteString(strings.Repeat(" ", HelpColumn))
+		}
+		for j, line := range wrap(paragraph, HelpWidth-HelpColumn) {
+			if j > 0 {
+				sb.WriteString(strings.Repeat(" ", HelpColumn))
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// wrap splits the text into lines of at most width characters at the spaces, a longer word is not split.
+func wrap(text string, width int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	line := words[0]
+	for _, word := range words[1:] {
+		if len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
@@ -0,0 +1,50 @@
+package alflag
+
+// SetParent sets the flag set of the parent command, e.g. the flag set of "emit cache" for "emit cache list".
+// The persistent flags of the parent and its ancestors are inherited, they can be given in the arguments of
+// this flag set and are shown in the help text.
+func (f *FlagSet) SetParent(parent *FlagSet) {
+	f.parent = parent
+}
+
+// MarkPersistent marks the registered flags as persistent, they are inherited by the flag sets of the
+// subcommands, see [FlagSet.SetParent]. The environment variables and the constraints of a persistent flag
+// are handled when the flag set defining it is parsed.
+func (f *FlagSet) MarkPersistent(names ...string) {
+	for _, name := range names {
+		f.mustLookupName(name).persistent = true
+	}
+}
+
+// InheritedFlags returns the metadata of the persistent flags of the ancestors, the closest ancestor first.
+// A flag shadowed by a flag of a closer flag set is not included.
+func (f *FlagSet) InheritedFlags() []Flag {
+	var flags []Flag
+	for fs := f.parent; fs != nil; fs = fs.parent {
+		for _, fl := range fs.Flags() {
+			shadowed := (fl.Short != "" && f.owner(fl.Short, false) != fs) || (fl.Long != "" && f.owner(fl.Long, true) != fs)
+			if fl.Persistent && !shadowed {
+				flags = append(flags, fl)
+			}
+		}
+	}
+	return flags
+}
+
+// owner returns the flag set defining the short or long name, which is the flag set itself or the closest
+// ancestor with the persistent flag, nil is returned if the name is not defined.
+func (f *FlagSet) owner(name string, long bool) *FlagSet {
+	for fs := f; fs != nil; fs = fs.parent {
+		names := fs.shorts
+		if long {
+			names = fs.longs
+		}
+		if _, ok := names[name]; !ok {
+			continue
+		}
+		if fs == f || fs.lookupName(name).persistent {
+			return fs
+		}
+	}
+	return nil
+}
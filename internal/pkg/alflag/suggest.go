@@ -0,0 +1,64 @@
+package alflag
+
+import (
+	"slices"
+	"strings"
+)
+
+// Suggest returns the candidates similar to the name, the closest first. A candidate is similar if the name is
+// its prefix, or their edit distance is at most a third of the length of the name plus one, e.g. "degti" is
+// similar to "degit" and "dryrun" is similar to "dry-run".
+func Suggest(name string, candidates []string) []string {
+	type suggestion struct {
+		name     string
+		distance int
+	}
+
+	var suggestions []suggestion
+	for _, candidate := range candidates {
+		if candidate == name || name == "" {
+			continue
+		}
+		d := distance(name, candidate)
+		if strings.HasPrefix(candidate, name) || d <= len(name)/3+1 {
+			suggestions = append(suggestions, suggestion{candidate, d})
+		}
+	}
+	slices.SortStableFunc(suggestions, func(a, b suggestion) int {
+		return a.distance - b.distance
+	})
+
+	var names []string
+	for _, s := range suggestions {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// distance returns the edit distance of the strings, counting an insertion, a deletion, a substitution and a
+// transposition of two adjacent characters as one edit.
+func distance(a, b string) int {
+	// d[i][j] is the distance of a[:i] and b[:j]
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
@@ -1,5 +1,95 @@
 package alflag
 
-import "flag"
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
 
 type Value = flag.Value
+
+// stringSliceValue collects every occurrence of a flag. The first occurrence replaces the default value, so that
+// the default is not merged with the given values.
+type stringSliceValue struct {
+	values  *[]string
+	split   bool
+	changed bool
+}
+
+func newStringSliceValue(p *[]string, value []string, split bool) *stringSliceValue {
+	*p = append([]string(nil), value...)
+	return &stringSliceValue{values: p, split: split}
+}
+
+func (s *stringSliceValue) String() string {
+	if s.values == nil {
+		return ""
+	}
+	return strings.Join(*s.values, ",")
+}
+
+func (s *stringSliceValue) Set(value string) error {
+	if !s.changed {
+		*s.values = nil
+		s.changed = true
+	}
+	if !s.split {
+		*s.values = append(*s.values, value)
+		return nil
+	}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); len(v) != 0 {
+			*s.values = append(*s.values, v)
+		}
+	}
+	return nil
+}
+
+// stringMapValue collects the "key=value" pairs of every occurrence of a flag, a later pair replaces the value of
+// the same key. The first occurrence replaces the default value.
+type stringMapValue struct {
+	values  *map[string]string
+	split   bool
+	changed bool
+}
+
+func newStringMapValue(p *map[string]string, value map[string]string, split bool) *stringMapValue {
+	*p = maps.Clone(value)
+	if *p == nil {
+		*p = make(map[string]string)
+	}
+	return &stringMapValue{values: p, split: split}
+}
+
+func (s *stringMapValue) String() string {
+	if s.values == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(*s.values))
+	for _, key := range slices.Sorted(maps.Keys(*s.values)) {
+		pairs = append(pairs, key+"="+(*s.values)[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (s *stringMapValue) Set(value string) error {
+	pairs := []string{value}
+	if s.split {
+		pairs = strings.Split(value, ",")
+	}
+
+	if !s.changed {
+		*s.values = make(map[string]string)
+		s.changed = true
+	}
+	for _, pair := range pairs {
+		key, v, ok := strings.Cut(pair, "=")
+		if !ok || len(key) == 0 {
+			return fmt.Errorf("expect <key>=<value>, got %q", pair)
+		}
+		(*s.values)[key] = v
+	}
+	return nil
+}
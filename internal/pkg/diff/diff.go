@@ -0,0 +1,152 @@
+// Package diff compares and merges texts by lines.
+package diff
+
+import (
+	"strings"
+)
+
+// Chunk is a change replacing the lines A[A0:A1] of the old text with the lines B[B0:B1] of the new text.
+// A0 == A1 for insertions, and B0 == B1 for deletions.
+type Chunk struct {
+	A0, A1 int
+	B0, B1 int
+}
+
+// SplitLines splits the text into lines, each line keeps its "\n" terminator. The last line has no terminator
+// if the text does not end with a newline.
+func SplitLines(text string) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Diff returns the chunks changing a into b, ordered by position. It uses the Myers algorithm with its linear space
+// refinement, so the chunks are a shortest edit script and the memory is proportional to the length of the texts.
+func Diff(a, b []string) []Chunk {
+	var chunks []Chunk
+	compare(a, b, 0, 0, &chunks)
+
+	// Join the adjacent chunks of the halves compared separately
+	var joined []Chunk
+	for _, c := range chunks {
+		if len(joined) != 0 {
+			last := &joined[len(joined)-1]
+			if last.A1 == c.A0 && last.B1 == c.B0 {
+				last.A1, last.B1 = c.A1, c.B1
+				continue
+			}
+		}
+		joined = append(joined, c)
+	}
+	return joined
+}
+
+// compare appends the chunks changing a into b, which start at the lines a0 and b0 of the whole texts.
+// The texts are split at a point of a shortest edit script, and the halves are compared recursively.
+func compare(a, b []string, a0, b0 int, chunks *[]Chunk) {
+	// The common prefix and suffix are not part of any chunk, trimming them keeps the search small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	a0, b0 = a0+prefix, b0+prefix
+
+	if len(a) == 0 || len(b) == 0 {
+		if len(a) != 0 || len(b) != 0 {
+			*chunks = append(*chunks, Chunk{a0, a0 + len(a), b0, b0 + len(b)})
+		}
+		return
+	}
+
+	x, y := bisect(a, b)
+	compare(a[:x], b[:y], a0, b0, chunks)
+	compare(a[x:], b[y:], a0+x, b0+y, chunks)
+}
+
+// bisect returns the point where the furthest reaching paths from the start and from the end of the texts meet,
+// by the greedy algorithm of "An O(ND) Difference Algorithm and Its Variations" run in both directions. The
+// point is on a shortest edit script. The texts do not start or end with a common line.
+func bisect(a, b []string) (int, int) {
+	n, m := len(a), len(b)
+	max := (n + m + 1) / 2
+	offset := max
+	// forward[offset+k] is the furthest x of the diagonal k from the start, backward[offset+k] is the furthest
+	// distance from the end of the diagonal k of the reversed texts; -1 if it is not reached yet
+	forward := make([]int, 2*max+2)
+	backward := make([]int, 2*max+2)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	delta := n - m
+	// The paths meet in the forward step if the difference of the lengths is odd, in the backward step otherwise
+	odd := delta%2 != 0
+	// The diagonals running out of the texts are skipped at both ends
+	kStart, kEnd, rStart, rEnd := 0, 0, 0, 0
+	for d := 0; d < max; d++ {
+		for k := -d + kStart; k <= d-kEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			switch {
+			case x > n:
+				kEnd += 2
+			case y > m:
+				kStart += 2
+			case odd:
+				if i := offset + delta - k; i >= 0 && i < len(backward) && backward[i] != -1 && x >= n-backward[i] {
+					return x, y
+				}
+			}
+		}
+
+		for k := -d + rStart; k <= d-rEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			switch {
+			case x > n:
+				rEnd += 2
+			case y > m:
+				rStart += 2
+			case !odd:
+				if i := offset + delta - k; i >= 0 && i < len(forward) && forward[i] != -1 && forward[i] >= n-x {
+					fx := forward[i]
+					return fx, fx - (i - offset)
+				}
+			}
+		}
+	}
+
+	// The paths always meet within the maximum distance, the texts are replaced as a whole otherwise
+	return n, 0
+}
@@ -0,0 +1,198 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// apply applies the chunks of Diff(a, b) to a.
+func apply(a, b []string, chunks []Chunk) []string {
+	var result []string
+	pos := 0
+	for _, c := range chunks {
+		result = append(result, a[pos:c.A0]...)
+		result = append(result, b[c.B0:c.B1]...)
+		pos = c.A1
+	}
+	return append(result, a[pos:]...)
+}
+
+func TestDiff(t *testing.T) {
+	type testcase struct {
+		a, b string
+		// edits is the number of deleted and inserted lines of the shortest edit script
+		edits int
+	}
+
+	tests := []testcase{
+		{"", "", 0},
+		{"a\n", "a\n", 0},
+		{"", "a\nb\n", 2},
+		{"a\nb\n", "", 2},
+		{"a\nb\nc\n", "a\nc\n", 1},
+		{"a\nc\n", "a\nb\nc\n", 1},
+		{"a\nb\nc\n", "a\nx\nc\n", 2},
+		{"a\nb\nc\na\nb\nb\na\n", "c\nb\na\nb\na\nc\n", 5},
+		{"a\nb", "a\nb\n", 2},
+	}
+
+	for _, test := range tests {
+		a, b := SplitLines(test.a), SplitLines(test.b)
+		chunks := Diff(a, b)
+
+		if got := strings.Join(apply(a, b, chunks), ""); got != test.b {
+			t.Errorf("Diff(%q, %q) applied = %q; want %q", test.a, test.b, got, test.b)
+		}
+		edits := 0
+		for _, c := range chunks {
+			edits += c.A1 - c.A0 + c.B1 - c.B0
+		}
+		if edits != test.edits {
+			t.Errorf("Diff(%q, %q) edits = %d; want %d", test.a, test.b, edits, test.edits)
+		}
+	}
+}
+
+// lcs returns the length of the longest common subsequence of the lines by dynamic programming.
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestDiff_random(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	lines := func() []string {
+		text := make([]string, r.IntN(30))
+		for i := range text {
+			text[i] = string(rune('a'+r.IntN(4))) + "\n"
+		}
+		return text
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := lines(), lines()
+		chunks := Diff(a, b)
+		if got := apply(a, b, chunks); !slices.Equal(got, b) {
+			t.Fatalf("Diff(%q, %q) applied = %q; want %q", a, b, got, b)
+		}
+		edits := 0
+		for j, c := range chunks {
+			edits += c.A1 - c.A0 + c.B1 - c.B0
+			if j > 0 && (c.A0 <= chunks[j-1].A1 && c.B0 <= chunks[j-1].B1) {
+				t.Fatalf("Diff(%q, %q) chunks are not ordered and separate: %v", a, b, chunks)
+			}
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+			t.Fatalf("Diff(%q, %q) edits = %d; want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestDiff_rewritten(t *testing.T) {
+	// A fully rewritten text has the longest edit script, the memory of the search must not grow with its length
+	const n = 5000
+	a, b := make([]string, n), make([]string, n)
+	for i := range n {
+		a[i] = fmt.Sprintf("old %d\n", i)
+		b[i] = fmt.Sprintf("new %d\n", i)
+	}
+	b[n/2] = a[n/2]
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	chunks := Diff(a, b)
+	runtime.ReadMemStats(&after)
+	// A trace of the whole search would take n*n words, hundreds of megabytes
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1<<20 {
+		t.Errorf("Diff() allocated %d bytes; want fewer than %d", alloc, 1<<20)
+	}
+
+	want := []Chunk{{0, n / 2, 0, n / 2}, {n/2 + 1, n, n/2 + 1, n}}
+	if !slices.Equal(chunks, want) {
+		t.Errorf("Diff() = %v; want %v", chunks, want)
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	type testcase struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		conflicts          int
+	}
+
+	tests := []testcase{
+		{"unchanged", "a\nb\n", "a\nb\n", "a\nb\n", "a\nb\n", 0},
+		{"ours", "a\nb\nc\n", "a\nB\nc\n", "a\nb\nc\n", "a\nB\nc\n", 0},
+		{"theirs", "a\nb\nc\n", "a\nb\nc\n", "a\nb\nC\n", "a\nb\nC\n", 0},
+		{"both", "a\nb\nc\nd\ne\n", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n", 0},
+		{"same change", "a\nb\nc\n", "a\nB\nc\n", "a\nB\nc\n", "a\nB\nc\n", 0},
+		{"insertions", "a\nb\nc\n", "x\na\nb\nc\n", "a\nb\nc\ny\n", "x\na\nb\nc\ny\n", 0},
+		{
+			"conflict", "a\nb\nc\n", "a\nours\nc\n", "a\ntheirs\nc\n",
+			"a\n<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\nc\n", 1,
+		},
+		{
+			"no newline", "a", "b", "c",
+			"<<<<<<< ours\nb\n=======\nc\n>>>>>>> theirs\n", 1,
+		},
+		{
+			"delete and change", "a\nb\nc\n", "a\nc\n", "a\nB\nc\n",
+			"a\n<<<<<<< ours\n=======\nB\n>>>>>>> theirs\nc\n", 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			regions := Merge3(SplitLines(test.base), SplitLines(test.ours), SplitLines(test.theirs))
+			if got := Markers(regions, "ours", "theirs"); got != test.want {
+				t.Errorf("Markers() = %q; want %q", got, test.want)
+			}
+			if got := Conflicts(regions); got != test.conflicts {
+				t.Errorf("Conflicts() = %d; want %d", got, test.conflicts)
+			}
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := SplitLines("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")
+	b := SplitLines("1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\neleven")
+
+	want := `--- a/file
++++ b/file
+@@ -1,5 +1,5 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+@@ -9,2 +9,3 @@
+ 9
+ 10
++eleven
+\ No newline at end of file
+`
+	if got := Unified("a/file", "b/file", a, b, 2); got != want {
+		t.Errorf("Unified() = %q; want %q", got, want)
+	}
+	if got := Unified("a/file", "b/file", a, a, 3); got != "" {
+		t.Errorf("Unified() of equal texts = %q; want empty", got)
+	}
+}
@@ -0,0 +1,196 @@
+package diff
+
+import (
+	"strings"
+)
+
+// Region is a part of the result of a three-way merge, either merged cleanly or in conflict.
+type Region struct {
+	// Conflict reports whether both sides changed the region differently
+	Conflict bool
+	// Lines are the merged lines of a clean region
+	Lines []string
+
+	// BaseLine is the index of the first line of the region in the base
+	BaseLine int
+	// Base, Ours and Theirs are the lines of a conflict on each side
+	Base, Ours, Theirs []string
+}
+
+// Merge3 merges the changes from base to ours and from base to theirs. The changes of one side are taken
+// if the other side did not change the same lines, the changes are taken once if both sides made the same,
+// otherwise the lines are in conflict.
+func Merge3(base, ours, theirs []string) []Region {
+	oursChunks := Diff(base, ours)
+	theirsChunks := Diff(base, theirs)
+
+	var regions []Region
+	appendLines := func(lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		if n := len(regions); n != 0 && !regions[n-1].Conflict {
+			regions[n-1].Lines = append(regions[n-1].Lines, lines...)
+			return
+		}
+		regions = append(regions, Region{Lines: append([]string(nil), lines...)})
+	}
+
+	pos := 0
+	i, j := 0, 0
+	for i < len(oursChunks) || j < len(theirsChunks) {
+		// Start the group by the first chunk of either side, and extend it by the chunks of both sides
+		// overlapping or touching it
+		var groupOurs, groupTheirs []Chunk
+		var lo, hi int
+		if j == len(theirsChunks) || (i < len(oursChunks) && oursChunks[i].A0 <= theirsChunks[j].A0) {
+			lo, hi = oursChunks[i].A0, oursChunks[i].A1
+			groupOurs = append(groupOurs, oursChunks[i])
+			i++
+		} else {
+			lo, hi = theirsChunks[j].A0, theirsChunks[j].A1
+			groupTheirs = append(groupTheirs, theirsChunks[j])
+			j++
+		}
+		for {
+			if i < len(oursChunks) && oursChunks[i].A0 <= hi {
+				hi = max(hi, oursChunks[i].A1)
+				groupOurs = append(groupOurs, oursChunks[i])
+				i++
+			} else if j < len(theirsChunks) && theirsChunks[j].A0 <= hi {
+				hi = max(hi, theirsChunks[j].A1)
+				groupTheirs = append(groupTheirs, theirsChunks[j])
+				j++
+			} else {
+				break
+			}
+		}
+
+		appendLines(base[pos:lo])
+		pos = hi
+
+		oursLines := side(ours, groupOurs, base, lo, hi)
+		theirsLines := side(theirs, groupTheirs, base, lo, hi)
+		switch {
+		case len(groupTheirs) == 0:
+			appendLines(oursLines)
+		case len(groupOurs) == 0:
+			appendLines(theirsLines)
+		case equal(oursLines, theirsLines):
+			appendLines(oursLines)
+		default:
+			regions = append(regions, Region{
+				Conflict: true,
+				BaseLine: lo,
+				Base:     base[lo:hi],
+				Ours:     oursLines,
+				Theirs:   theirsLines,
+			})
+		}
+	}
+	appendLines(base[pos:])
+	return regions
+}
+
+// side returns the lines of one side replacing the base lines [lo, hi) by its chunks of the group.
+func side(lines []string, chunks []Chunk, base []string, lo, hi int) []string {
+	if len(chunks) == 0 {
+		return base[lo:hi]
+	}
+	first, last := chunks[0], chunks[len(chunks)-1]
+	return lines[first.B0-(first.A0-lo) : last.B1+(hi-last.A1)]
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Conflicts returns how many regions are in conflict.
+func Conflicts(regions []Region) int {
+	n := 0
+	for _, region := range regions {
+		if region.Conflict {
+			n++
+		}
+	}
+	return n
+}
+
+// Markers returns the merged text with the conflicts between conflict markers, labeled by the names of
+// the sides:
+//
+//	<<<<<<< ours
+//	=======
+//	>>>>>>> theirs
+func Markers(regions []Region, oursName, theirsName string) string {
+	var sb strings.Builder
+	for _, region := range regions {
+		if !region.Conflict {
+			writeLines(&sb, region.Lines)
+			continue
+		}
+		sb.WriteString("<<<<<<< " + oursName + "\n")
+		writeLines(&sb, region.Ours)
+		terminate(&sb)
+		sb.WriteString("=======\n")
+		writeLines(&sb, region.Theirs)
+		terminate(&sb)
+		sb.WriteString(">>>>>>> " + theirsName + "\n")
+	}
+	return sb.String()
+}
+
+// Rejects returns the changes of their side which are in conflict as hunks against the base, like the
+// rejected hunks of patch(1).
+func Rejects(regions []Region) []Hunk {
+	var hunks []Hunk
+	for _, region := range regions {
+		if !region.Conflict {
+			continue
+		}
+		hunk := Hunk{
+			A0: region.BaseLine,
+			A1: region.BaseLine + len(region.Base),
+			B0: region.BaseLine,
+			B1: region.BaseLine + len(region.Theirs),
+		}
+		hunk.Lines = appendPrefixed(hunk.Lines, "-", region.Base)
+		hunk.Lines = appendPrefixed(hunk.Lines, "+", region.Theirs)
+		hunks = append(hunks, hunk)
+	}
+	return hunks
+}
+
+// Ours returns the merged text keeping our side of the conflicts.
+func Ours(regions []Region) string {
+	var sb strings.Builder
+	for _, region := range regions {
+		if region.Conflict {
+			writeLines(&sb, region.Ours)
+		} else {
+			writeLines(&sb, region.Lines)
+		}
+	}
+	return sb.String()
+}
+
+func writeLines(sb *strings.Builder, lines []string) {
+	for _, line := range lines {
+		sb.WriteString(line)
+	}
+}
+
+// terminate ends the text with a newline so that a marker starts a line.
+func terminate(sb *strings.Builder) {
+	if s := sb.String(); len(s) != 0 && !strings.HasSuffix(s, "\n") {
+		sb.WriteByte('\n')
+	}
+}
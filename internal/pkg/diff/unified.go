@@ -0,0 +1,101 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hunk is a group of changes with their context lines in the unified format.
+type Hunk struct {
+	// A0, A1 and B0, B1 are the lines of the hunk in the old and new text
+	A0, A1 int
+	B0, B1 int
+	// Lines are the lines of the hunk prefixed by " " for context, "-" for deletions and "+" for insertions
+	Lines []string
+}
+
+// Hunks returns the hunks changing a into b with n lines of context around the changes. The changes closer
+// than 2n lines are in the same hunk.
+func Hunks(a, b []string, n int) []Hunk {
+	chunks := Diff(a, b)
+
+	var hunks []Hunk
+	for len(chunks) != 0 {
+		// Group the chunks whose contexts overlap
+		end := 1
+		for end < len(chunks) && chunks[end].A0-chunks[end-1].A1 <= 2*n {
+			end++
+		}
+		group := chunks[:end]
+		chunks = chunks[end:]
+
+		first, last := group[0], group[len(group)-1]
+		hunk := Hunk{
+			A0: max(first.A0-n, 0),
+			B0: max(first.B0-n, 0),
+		}
+		hunk.A1 = min(last.A1+n, len(a))
+		hunk.B1 = min(last.B1+n, len(b))
+
+		pos := hunk.A0
+		for _, c := range group {
+			hunk.Lines = appendPrefixed(hunk.Lines, " ", a[pos:c.A0])
+			hunk.Lines = appendPrefixed(hunk.Lines, "-", a[c.A0:c.A1])
+			hunk.Lines = appendPrefixed(hunk.Lines, "+", b[c.B0:c.B1])
+			pos = c.A1
+		}
+		hunk.Lines = appendPrefixed(hunk.Lines, " ", a[pos:hunk.A1])
+		hunks = append(hunks, hunk)
+	}
+	return hunks
+}
+
+func appendPrefixed(dst []string, prefix string, lines []string) []string {
+	for _, line := range lines {
+		dst = append(dst, prefix+line)
+	}
+	return dst
+}
+
+// String formats the hunk with its "@@" header, a line without a newline is followed by a
+// "\ No newline at end of file" line.
+func (h Hunk) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(h.A0, h.A1), hunkRange(h.B0, h.B1))
+	for _, line := range h.Lines {
+		sb.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+	return sb.String()
+}
+
+// hunkRange formats the lines [start, end) as the 1-based "start,count" of the unified format.
+func hunkRange(start, end int) string {
+	count := end - start
+	if count == 0 {
+		// An empty range is positioned at the line before it
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// Unified returns the unified diff changing a into b with n lines of context, the names are shown in the
+// "---" and "+++" header. An empty string is returned if a and b are equal.
+func Unified(aName, bName string, a, b []string, n int) string {
+	hunks := Hunks(a, b, n)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+	for _, hunk := range hunks {
+		sb.WriteString(hunk.String())
+	}
+	return sb.String()
+}
@@ -0,0 +1,301 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+)
+
+type operator int
+
+const (
+	opEQ operator = iota
+	opGT
+	opGTE
+	opLT
+	opLTE
+)
+
+type comparator struct {
+	op      operator
+	version Version
+}
+
+func (c comparator) matches(v Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case opGT:
+		return cmp > 0
+	case opGTE:
+		return cmp >= 0
+	case opLT:
+		return cmp < 0
+	case opLTE:
+		return cmp <= 0
+	}
+	return cmp == 0
+}
+
+// Range is a set of version constraints, written in the syntax used by npm, e.g. "^1.2", "~1.2.3 || >=2.0.0 <3",
+// "1.x" or "1.2.0 - 1.4". A leading "v" of each version is tolerated.
+//
+// A prerelease version is only contained by the range if a constraint refers to a prerelease of
+// the same MAJOR.MINOR.PATCH, e.g. "1.2.3-beta.2" is contained by ">=1.2.3-beta.1" but not by ">=1.2.0".
+type Range struct {
+	sets [][]comparator
+}
+
+// ParseRange parses the range string.
+func ParseRange(s string) (Range, error) {
+	var r Range
+	for _, part := range strings.Split(s, "||") {
+		set, err := parseComparatorSet(strings.TrimSpace(part))
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid range '%s': %w", s, err)
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+// Contains reports whether the version satisfies the range.
+func (r Range) Contains(v Version) bool {
+	for _, set := range r.sets {
+		if setContains(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func setContains(set []comparator, v Version) bool {
+	for _, c := range set {
+		if !c.matches(v) {
+			return false
+		}
+	}
+	if !v.IsPrerelease() {
+		return true
+	}
+
+	for _, c := range set {
+		cv := c.version
+		if cv.IsPrerelease() && cv.Major == v.Major && cv.Minor == v.Minor && cv.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+func parseComparatorSet(s string) ([]comparator, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return []comparator{{opGTE, Version{}}}, nil
+	}
+
+	// Hyphen range: "1.2.3 - 2.3.4"
+	if len(fields) == 3 && fields[1] == "-" {
+		from, err := parsePartial(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		to, err := parsePartial(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		set := []comparator{{opGTE, from.lower()}}
+		if to.isFull() {
+			return append(set, comparator{opLTE, to.lower()}), nil
+		}
+		if upper, ok := to.upper(); ok {
+			set = append(set, comparator{opLT, withPrerelease0(upper)})
+		}
+		return set, nil
+	}
+
+	var set []comparator
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		// Allow a space between the operator and the version, e.g. ">= 1.2.3"
+		if strings.Trim(field, "<>=~^") == "" && i+1 < len(fields) {
+			i++
+			field += fields[i]
+		}
+
+		comparators, err := parseComparator(field)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, comparators...)
+	}
+	return set, nil
+}
+
+func parseComparator(s string) ([]comparator, error) {
+	prefix := ""
+	for _, op := range []string{">=", "<=", ">", "<", "=", "~", "^"} {
+		if strings.HasPrefix(s, op) {
+			prefix = op
+			break
+		}
+	}
+
+	p, err := parsePartial(strings.TrimPrefix(s, prefix))
+	if err != nil {
+		return nil, err
+	}
+	lower := p.lower()
+	upper, bounded := p.upper()
+
+	switch prefix {
+	case "", "=":
+		if p.isFull() {
+			return []comparator{{opEQ, lower}}, nil
+		}
+		return between(lower, upper, bounded), nil
+	case ">":
+		if p.isFull() {
+			return []comparator{{opGT, lower}}, nil
+		}
+		if !bounded {
+			// Nothing is greater than "*"
+			return []comparator{{opLT, Version{}}}, nil
+		}
+		return []comparator{{opGTE, upper}}, nil
+	case ">=":
+		return []comparator{{opGTE, lower}}, nil
+	case "<":
+		if p.isFull() {
+			return []comparator{{opLT, lower}}, nil
+		}
+		return []comparator{{opLT, withPrerelease0(lower)}}, nil
+	case "<=":
+		if p.isFull() {
+			return []comparator{{opLTE, lower}}, nil
+		}
+		if !bounded {
+			return []comparator{{opGTE, Version{}}}, nil
+		}
+		return []comparator{{opLT, withPrerelease0(upper)}}, nil
+	case "~":
+		if p.minor == nil {
+			return between(lower, upper, bounded), nil
+		}
+		return []comparator{{opGTE, lower}, {opLT, withPrerelease0(Version{Major: lower.Major, Minor: lower.Minor + 1})}}, nil
+	case "^":
+		var upper Version
+		switch {
+		case p.major == nil:
+			return []comparator{{opGTE, Version{}}}, nil
+		case lower.Major != 0 || p.minor == nil:
+			upper = Version{Major: lower.Major + 1}
+		case lower.Minor != 0 || p.patch == nil:
+			upper = Version{Minor: lower.Minor + 1}
+		default:
+			upper = Version{Patch: lower.Patch + 1}
+		}
+		return []comparator{{opGTE, lower}, {opLT, withPrerelease0(upper)}}, nil
+	}
+	return nil, fmt.Errorf("invalid comparator '%s'", s)
+}
+
+// partial is a version which may omit or wildcard its trailing components, e.g. "1", "1.2" or "1.x".
+type partial struct {
+	major *uint64
+	minor *uint64
+	patch *uint64
+
+	prerelease []string
+}
+
+func parsePartial(s string) (partial, error) {
+	var p partial
+
+	str := strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	if i := strings.IndexByte(str, '+'); i >= 0 {
+		str = str[:i]
+	}
+	if i := strings.IndexByte(str, '-'); i >= 0 {
+		p.prerelease = strings.Split(str[i+1:], ".")
+		str = str[:i]
+	}
+
+	parts := strings.Split(str, ".")
+	if len(parts) > 3 {
+		return partial{}, fmt.Errorf("invalid version '%s'", s)
+	}
+	numbers := []**uint64{&p.major, &p.minor, &p.patch}
+	wildcard := false
+	for i, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			wildcard = true
+			continue
+		}
+		if wildcard {
+			return partial{}, fmt.Errorf("invalid version '%s': number after wildcard", s)
+		}
+		n, err := parseNumber(part)
+		if err != nil {
+			return partial{}, fmt.Errorf("invalid version '%s': %w", s, err)
+		}
+		*numbers[i] = &n
+	}
+
+	if len(p.prerelease) != 0 {
+		if !p.isFull() {
+			return partial{}, fmt.Errorf("invalid version '%s': prerelease requires MAJOR.MINOR.PATCH", s)
+		}
+		if _, err := Parse(s); err != nil {
+			return partial{}, err
+		}
+	}
+	return p, nil
+}
+
+func (p partial) isFull() bool {
+	return p.patch != nil
+}
+
+// lower returns the lowest version matched by the partial version.
+func (p partial) lower() Version {
+	v := Version{Prerelease: p.prerelease}
+	if p.major != nil {
+		v.Major = *p.major
+	}
+	if p.minor != nil {
+		v.Minor = *p.minor
+	}
+	if p.patch != nil {
+		v.Patch = *p.patch
+	}
+	return v
+}
+
+// upper returns the lowest version greater than all versions matched by the partial version,
+// false is returned for the unbounded "*".
+func (p partial) upper() (Version, bool) {
+	switch {
+	case p.major == nil:
+		return Version{}, false
+	case p.minor == nil:
+		return Version{Major: *p.major + 1}, true
+	case p.patch == nil:
+		return Version{Major: *p.major, Minor: *p.minor + 1}, true
+	}
+	return Version{Major: *p.major, Minor: *p.minor, Patch: *p.patch + 1}, true
+}
+
+// between returns the comparators for the versions from lower (inclusive) to upper (exclusive).
+func between(lower Version, upper Version, bounded bool) []comparator {
+	set := []comparator{{opGTE, lower}}
+	if bounded {
+		set = append(set, comparator{opLT, withPrerelease0(upper)})
+	}
+	return set
+}
+
+// withPrerelease0 returns the lowest prerelease of the version, so that the prereleases of
+// an excluded upper bound are excluded as well.
+func withPrerelease0(v Version) Version {
+	v.Prerelease = []string{"0"}
+	return v
+}
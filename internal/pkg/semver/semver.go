@@ -0,0 +1,137 @@
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version as described by https://semver.org.
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string
+	Build      string
+}
+
+// Parse parses the version string. A leading "v" or "V" is tolerated, e.g. "v1.2.3".
+func Parse(s string) (Version, error) {
+	var v Version
+
+	str := strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	if i := strings.IndexByte(str, '+'); i >= 0 {
+		v.Build = str[i+1:]
+		str = str[:i]
+		if len(v.Build) == 0 {
+			return Version{}, fmt.Errorf("invalid version '%s': empty build metadata", s)
+		}
+	}
+	if i := strings.IndexByte(str, '-'); i >= 0 {
+		v.Prerelease = strings.Split(str[i+1:], ".")
+		str = str[:i]
+		for _, id := range v.Prerelease {
+			if len(id) == 0 {
+				return Version{}, fmt.Errorf("invalid version '%s': empty prerelease identifier", s)
+			}
+		}
+	}
+
+	parts := strings.Split(str, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid version '%s': expect MAJOR.MINOR.PATCH", s)
+	}
+	numbers := []*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := parseNumber(part)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version '%s': %w", s, err)
+		}
+		*numbers[i] = n
+	}
+
+	return v, nil
+}
+
+// IsPrerelease reports whether the version has prerelease identifiers, e.g. "1.0.0-rc.1".
+func (v Version) IsPrerelease() bool {
+	return len(v.Prerelease) != 0
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.IsPrerelease() {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if len(v.Build) != 0 {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0 or +1 if v is less than, equal to, or greater than other.
+// The build metadata is ignored as required by the specification.
+func (v Version) Compare(other Version) int {
+	if c := compareNumber(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareNumber(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareNumber(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+func parseNumber(s string) (uint64, error) {
+	if len(s) == 0 {
+		return 0, fmt.Errorf("empty number")
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("number '%s' has a leading zero", s)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func compareNumber(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// comparePrerelease compares the prerelease identifiers, a version without them has the higher precedence.
+func comparePrerelease(a, b []string) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a) && i < len(b); i++ {
+		an, aErr := strconv.ParseUint(a[i], 10, 64)
+		bn, bErr := strconv.ParseUint(b[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if c := compareNumber(an, bn); c != 0 {
+				return c
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareNumber(uint64(len(a)), uint64(len(b)))
+}
@@ -0,0 +1,134 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	type testcase struct {
+		input   string
+		want    string
+		wantErr bool
+	}
+
+	tests := []testcase{
+		{"1.2.3", "1.2.3", false},
+		{"v1.2.3", "1.2.3", false},
+		{"V0.0.1", "0.0.1", false},
+		{"1.0.0-rc.1", "1.0.0-rc.1", false},
+		{"1.0.0-alpha+build.5", "1.0.0-alpha+build.5", false},
+
+		{"1.2", "", true},
+		{"1.2.3.4", "", true},
+		{"01.2.3", "", true},
+		{"1.2.x", "", true},
+		{"1.2.3-", "", true},
+		{"1.2.3-a..b", "", true},
+		{"1.2.3+", "", true},
+		{"release-1", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			v, err := Parse(test.input)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Parse(%q) error = %v; wantErr %v", test.input, err, test.wantErr)
+			}
+			if err == nil && v.String() != test.want {
+				t.Errorf("Parse(%q) = %q; want %q", test.input, v.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	// Ordered by precedence, taken from the specification
+	versions := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+		"10.0.0",
+	}
+
+	for i := range versions {
+		for j := range versions {
+			a, _ := Parse(versions[i])
+			b, _ := Parse(versions[j])
+
+			want := compareNumber(uint64(i), uint64(j))
+			if got := a.Compare(b); got != want {
+				t.Errorf("Compare(%q, %q) = %d; want %d", versions[i], versions[j], got, want)
+			}
+		}
+	}
+
+	a, _ := Parse("1.0.0+build.1")
+	b, _ := Parse("1.0.0+build.2")
+	if a.Compare(b) != 0 {
+		t.Errorf("Compare(%q, %q) should ignore build metadata", a, b)
+	}
+}
+
+func TestRange_Contains(t *testing.T) {
+	type testcase struct {
+		input string
+		in    []string
+		out   []string
+	}
+
+	tests := []testcase{
+		{"1.2.3", []string{"1.2.3", "v1.2.3"}, []string{"1.2.4", "1.2.3-rc.1"}},
+		{"v1.2.3", []string{"1.2.3"}, []string{"1.2.2"}},
+		{"*", []string{"0.0.0", "1.2.3", "10.0.0"}, []string{"1.0.0-rc.1"}},
+		{"1.x", []string{"1.0.0", "1.9.9"}, []string{"0.9.9", "2.0.0", "2.0.0-rc.1"}},
+		{"1.2", []string{"1.2.0", "1.2.9"}, []string{"1.3.0", "1.1.9"}},
+		{"^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"1.2.2", "2.0.0", "2.0.0-alpha", "1.5.0-beta"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0", "0.2.2"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"^0", []string{"0.0.1", "0.9.0"}, []string{"1.0.0"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0", "1.2.2"}},
+		{"~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{">1.2", []string{"1.3.0"}, []string{"1.2.9"}},
+		{">=1.2", []string{"1.2.0", "3.0.0"}, []string{"1.1.9"}},
+		{"<1.2", []string{"1.1.9"}, []string{"1.2.0", "1.2.0-rc.1"}},
+		{"<=1.2", []string{"1.2.9"}, []string{"1.3.0"}},
+		{">= 1.0.0 < 2", []string{"1.0.0", "1.9.9"}, []string{"2.0.0"}},
+		{"1.2 - 2.3.4", []string{"1.2.0", "2.3.4"}, []string{"2.3.5", "1.1.9"}},
+		{"1.2.3 - 2.3", []string{"1.2.3", "2.3.9"}, []string{"2.4.0"}},
+		{"^1.2 || ^3", []string{"1.2.0", "3.1.0"}, []string{"2.0.0"}},
+		{">=1.2.3-beta.1 <1.3", []string{"1.2.3-beta.2", "1.2.3"}, []string{"1.2.4-beta.1", "1.2.3-alpha"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			r, err := ParseRange(test.input)
+			if err != nil {
+				t.Fatalf("ParseRange(%q) error: %v", test.input, err)
+			}
+			for _, s := range test.in {
+				v, _ := Parse(s)
+				if !r.Contains(v) {
+					t.Errorf("ParseRange(%q).Contains(%q) = false; want true", test.input, s)
+				}
+			}
+			for _, s := range test.out {
+				v, _ := Parse(s)
+				if r.Contains(v) {
+					t.Errorf("ParseRange(%q).Contains(%q) = true; want false", test.input, s)
+				}
+			}
+		})
+	}
+
+	for _, input := range []string{"main", "release-*", "1.2.3.4", "^x.1", ">=a", "1.2-rc.1"} {
+		if _, err := ParseRange(input); err == nil {
+			t.Errorf("ParseRange(%q) should fail", input)
+		}
+	}
+}
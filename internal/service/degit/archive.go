@@ -0,0 +1,424 @@
+package degit
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/klauspost/compress/zstd"
+)
+
+type Mode int
+
+const (
+	// ModeGit clones the repository with the git protocol, it is the default mode
+	ModeGit Mode = iota
+	// ModeTar downloads the archive of the resolved commit from the archive endpoint of the host,
+	// the submodules are not included and reported as skipped
+	ModeTar
+)
+
+const (
+	ArchiveTar    = "tar"
+	ArchiveTarGz  = "tar.gz"
+	ArchiveTarZst = "tar.zst"
+	ArchiveZip    = "zip"
+)
+
+// ParseMode parses the mode name: "git" or "tar".
+func ParseMode(name string) (Mode, error) {
+	switch name {
+	case "git":
+		return ModeGit, nil
+	case "tar":
+		return ModeTar, nil
+	}
+	return 0, fmt.Errorf("invalid mode '%s'", name)
+}
+
+// String returns the name of the mode, see [ParseMode].
+func (m Mode) String() string {
+	if m == ModeTar {
+		return "tar"
+	}
+	return "git"
+}
+
+// SetMode sets how a git remote is fetched.
+func (d *DegitService) SetMode(mode Mode) {
+	d.mode = mode
+}
+
+// ArchiveFormat returns the format of the archive by the extension of its path or URL, an empty string is
+// returned if the source is not an archive.
+func ArchiveFormat(source string) string {
+	name := source
+	if u, err := url.Parse(source); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		name = u.Path
+	}
+	name = strings.ToLower(name)
+
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ArchiveTarGz
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return ArchiveTarZst
+	case strings.HasSuffix(name, ".zip"):
+		return ArchiveZip
+	case strings.HasSuffix(name, ".tar"):
+		return ArchiveTar
+	}
+	return ""
+}
+
+// archiveURL returns the URL of the archive of the commit served by the host of the remote.
+// GitHub, GitLab and Bitbucket are supported.
+func archiveURL(remote string, commit plumbing.Hash) (string, error) {
+	endpoint, err := transport.NewEndpoint(remote)
+	if err != nil {
+		return "", err
+	}
+	repoPath := strings.TrimSuffix(strings.Trim(endpoint.Path, "/"), ".git")
+	if endpoint.Protocol == "file" || !strings.Contains(repoPath, "/") {
+		return "", fmt.Errorf("no archive endpoint for '%s'", remote)
+	}
+
+	switch endpoint.Host {
+	case "github.com":
+		return fmt.Sprintf("https://codeload.github.com/%s/tar.gz/%s", repoPath, commit), nil
+	case "gitlab.com":
+		return fmt.Sprintf("https://gitlab.com/%s/-/archive/%s/%s-%s.tar.gz", repoPath, commit, path.Base(repoPath), commit), nil
+	case "bitbucket.org":
+		return fmt.Sprintf("https://bitbucket.org/%s/get/%s.tar.gz", repoPath, commit), nil
+	}
+	return "", fmt.Errorf("no archive endpoint for the host '%s'", endpoint.Host)
+}
+
+// fetchArchive reads the archive from the local path or downloads it from the HTTP URL, then extracts it into fs.
+func (d *DegitService) fetchArchive(ctx context.Context, fs billy.Filesystem, source string, format string) error {
+	var r io.ReadCloser
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		d.log("download archive: %s", source)
+		r, err = d.downloadArchive(ctx, source)
+	} else {
+		p, ok := LocalPath(source)
+		if !ok {
+			p = source
+		}
+		d.log("read archive: %s", p)
+		r, err = os.Open(p)
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := extractArchive(fs, r, format); err != nil {
+		return fmt.Errorf("invalid archive: %w", err)
+	}
+	return nil
+}
+
+// downloadArchive returns the body of the archive at the URL. The credentials of the remote are only sent to its
+// host, the archive endpoint of a hosting service may be another host, e.g. "codeload.github.com".
+func (d *DegitService) downloadArchive(ctx context.Context, u string) (io.ReadCloser, error) {
+	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint, err := transport.NewEndpoint(d.remote); err == nil && strings.EqualFold(endpoint.Host, req.URL.Hostname()) {
+		authorize(req, d.authMethod)
+	}
+
+	resp, err := nethttp.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStatus(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// extractArchive extracts the files and symlinks of the archive into fs, the hard links of a tar archive are
+// extracted as copies of their files. Directories and other entries are ignored. The entries of a tar archive
+// are streamed, a zip archive is read from a temporary file unless r is a file, since it needs random access.
+func extractArchive(fs billy.Filesystem, r io.Reader, format string) error {
+	x := &archiveExtractor{fs: fs, modes: make(map[string]os.FileMode)}
+	switch format {
+	case ArchiveTar:
+	case ArchiveTarGz:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case ArchiveTarZst:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	case ArchiveZip:
+		if err := x.extractZip(r); err != nil {
+			return err
+		}
+		return x.finish()
+	default:
+		return fmt.Errorf("unsupported archive format '%s'", format)
+	}
+
+	if err := x.extractTar(r); err != nil {
+		return err
+	}
+	return x.finish()
+}
+
+// archiveExtractor writes the entries of an archive into fs as they are read. The top-level directory and the
+// symlinks are handled once all the entries are known, see [archiveExtractor.finish].
+type archiveExtractor struct {
+	fs billy.Filesystem
+	// modes maps the names of the extracted files to their permissions, for the hard links
+	modes    map[string]os.FileMode
+	symlinks []archiveSymlink
+	// top is the top-level directory containing all the entries so far, e.g. "repo-1.0.0"
+	top  string
+	seen bool
+}
+
+type archiveSymlink struct {
+	name   string
+	target string
+}
+
+func (x *archiveExtractor) extractTar(r io.Reader) error {
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name, err := archivePath(header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeReg:
+			err = x.writeFile(name, os.FileMode(header.Mode).Perm(), reader)
+		case tar.TypeLink:
+			// A hard link refers to a file earlier in the archive
+			target, pathErr := archivePath(header.Linkname)
+			if pathErr != nil {
+				return pathErr
+			}
+			err = x.hardLink(name, target)
+		case tar.TypeSymlink:
+			x.symlink(name, header.Linkname)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (x *archiveExtractor) extractZip(r io.Reader) error {
+	f, ok := r.(*os.File)
+	if !ok {
+		tmp, err := os.CreateTemp("", "emit-*.zip")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		if _, err := io.Copy(tmp, r); err != nil {
+			return err
+		}
+		f = tmp
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	reader, err := zip.NewReader(f, fi.Size())
+	if err != nil {
+		return err
+	}
+
+	for _, file := range reader.File {
+		mode := file.Mode()
+		if !mode.IsRegular() && mode&os.ModeSymlink == 0 {
+			continue
+		}
+		name, err := archivePath(file.Name)
+		if err != nil {
+			return err
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		if mode&os.ModeSymlink != 0 {
+			// The content of a symlink is its target, which is not longer than a path
+			target, err := io.ReadAll(io.LimitReader(rc, 4096))
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			x.symlink(name, string(target))
+			continue
+		}
+		err = x.writeFile(name, mode.Perm(), rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile writes the content of the file entry into fs.
+func (x *archiveExtractor) writeFile(name string, perm os.FileMode, r io.Reader) error {
+	if perm == 0 {
+		perm = 0644
+	}
+	if err := x.fs.MkdirAll(path.Dir(name), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := x.fs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	x.modes[name] = perm
+	x.add(name)
+	return nil
+}
+
+// hardLink copies the extracted file of the target to the name.
+func (x *archiveExtractor) hardLink(name string, target string) error {
+	perm, ok := x.modes[target]
+	if !ok {
+		return fmt.Errorf("hard link '%s' to missing file '%s'", name, target)
+	}
+	if name == target {
+		return nil
+	}
+	f, err := x.fs.Open(target)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return x.writeFile(name, perm, f)
+}
+
+// symlink records the symlink entry, it is created by [archiveExtractor.finish].
+func (x *archiveExtractor) symlink(name string, target string) {
+	x.symlinks = append(x.symlinks, archiveSymlink{name: name, target: target})
+	x.add(name)
+}
+
+// add updates the top-level directory with the name of an entry.
+func (x *archiveExtractor) add(name string) {
+	dir, _, ok := strings.Cut(name, "/")
+	if !ok || (x.seen && dir != x.top) {
+		x.top = ""
+	} else if !x.seen {
+		x.top = dir
+	}
+	x.seen = true
+}
+
+// finish strips the top-level directory if it contains all the entries, then creates the symlinks. The symlinks are
+// checked here, after the top-level directory is stripped, as a link may only escape the stripped root.
+func (x *archiveExtractor) finish() error {
+	if len(x.top) != 0 {
+		if err := x.strip(); err != nil {
+			return err
+		}
+	}
+
+	for _, link := range x.symlinks {
+		name := link.name
+		if len(x.top) != 0 {
+			name = strings.TrimPrefix(name, x.top+"/")
+		}
+		if err := archiveLink(name, link.target); err != nil {
+			return err
+		}
+		if err := x.fs.MkdirAll(path.Dir(name), os.ModePerm); err != nil {
+			return err
+		}
+		if err := x.fs.Symlink(link.target, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// strip moves the content of the top-level directory to the root of fs. The directory is renamed first, since it
+// may contain an entry of the same name; no other name is taken at the root.
+func (x *archiveExtractor) strip() error {
+	if _, err := x.fs.Lstat(x.top); errors.Is(err, os.ErrNotExist) {
+		// Only symlinks, which are not created yet
+		return nil
+	}
+	tmp := x.top + ".strip"
+	if err := x.fs.Rename(x.top, tmp); err != nil {
+		return err
+	}
+	infos, err := x.fs.ReadDir(tmp)
+	if err != nil {
+		return err
+	}
+	for _, fi := range infos {
+		if err := x.fs.Rename(path.Join(tmp, fi.Name()), fi.Name()); err != nil {
+			return err
+		}
+	}
+	return x.fs.Remove(tmp)
+}
+
+// archivePath cleans the path of an archive entry, paths escaping the archive are rejected.
+func archivePath(name string) (string, error) {
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("unsafe path '%s'", name)
+	}
+	return name, nil
+}
+
+// archiveLink checks the target of the symlink at the path, absolute targets and targets escaping the root are
+// rejected.
+func archiveLink(name string, target string) error {
+	if _, err := archivePath(path.Join(path.Dir(name), target)); err != nil || path.IsAbs(target) {
+		return fmt.Errorf("unsafe symlink '%s' -> '%s'", name, target)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package degit
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestArchiveFormat(t *testing.T) {
+	tests := map[string]string{
+		"starter.tar.gz":                       ArchiveTarGz,
+		"dist/starter.tgz":                     ArchiveTarGz,
+		"starter.tar.zst":                      ArchiveTarZst,
+		"https://example.com/starter.zip?x=1":  ArchiveZip,
+		"https://example.com/starter.ZIP":      ArchiveZip,
+		"https://github.com/user/repo.git":     "",
+		"https://example.com/download?f=a.zip": "",
+	}
+
+	for source, want := range tests {
+		if got := ArchiveFormat(source); got != want {
+			t.Errorf("ArchiveFormat(%q) = %q; want %q", source, got, want)
+		}
+	}
+}
+
+func TestArchiveURL(t *testing.T) {
+	commit := plumbing.NewHash("abc1000000000000000000000000000000000000")
+
+	tests := []struct {
+		remote string
+		want   string
+	}{
+		{"https://github.com/user/repo.git", "https://codeload.github.com/user/repo/tar.gz/" + commit.String()},
+		{"git@github.com:user/repo.git", "https://codeload.github.com/user/repo/tar.gz/" + commit.String()},
+		{"https://gitlab.com/group/sub/repo", "https://gitlab.com/group/sub/repo/-/archive/" + commit.String() + "/repo-" + commit.String() + ".tar.gz"},
+		{"https://bitbucket.org/user/repo.git", "https://bitbucket.org/user/repo/get/" + commit.String() + ".tar.gz"},
+		{"https://git.example.com/user/repo.git", ""},
+		{"/srv/git/repo.git", ""},
+	}
+
+	for _, test := range tests {
+		got, err := archiveURL(test.remote, commit)
+		if len(test.want) == 0 {
+			if err == nil {
+				t.Errorf("archiveURL(%q) = %q; want error", test.remote, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("archiveURL(%q) = %q, %v; want %q", test.remote, got, err, test.want)
+		}
+	}
+}
+
+func TestArchiveLink(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		valid  bool
+	}{
+		{"link", "README.md", true},
+		{"docs/link", "../README.md", true},
+		{"docs/link", "./api/../index.md", true},
+		{"a/b/link", "../../c", true},
+		{"link", "/etc/passwd", false},
+		{"docs/link", "/docs/README.md", false},
+		{"link", "../README.md", false},
+		{"docs/link", "../../README.md", false},
+		{"docs/link", "api/../../../x", false},
+		{"link", "..", false},
+	}
+
+	for _, test := range tests {
+		if err := archiveLink(test.name, test.target); (err == nil) != test.valid {
+			t.Errorf("archiveLink(%q, %q) error = %v; want valid %v", test.name, test.target, err, test.valid)
+		}
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	files := map[string]string{
+		"repo-1.0.0/README.md":  "readme",
+		"repo-1.0.0/src/main.c": "int main;",
+	}
+
+	var tarGz bytes.Buffer
+	gz := gzip.NewWriter(&tarGz)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header"})
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: int64(len(content))})
+		tw.Write([]byte(content))
+	}
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "repo-1.0.0/link", Linkname: "README.md"})
+	tw.Close()
+	gz.Close()
+
+	var zipData bytes.Buffer
+	zw := zip.NewWriter(&zipData)
+	for name, content := range files {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	w, _ := zw.Create("other.txt")
+	w.Write([]byte("other"))
+	zw.Close()
+
+	var unsafe bytes.Buffer
+	zw = zip.NewWriter(&unsafe)
+	zw.Create("../escape.txt")
+	zw.Close()
+
+	t.Run("tar.gz", func(t *testing.T) {
+		fs := memfs.New()
+		if err := extractArchive(fs, bytes.NewReader(tarGz.Bytes()), ArchiveTarGz); err != nil {
+			t.Fatal(err)
+		}
+		if data, err := util.ReadFile(fs, "src/main.c"); err != nil || string(data) != "int main;" {
+			t.Errorf("src/main.c = %q, %v; want %q", data, err, "int main;")
+		}
+		if target, err := fs.Readlink("link"); err != nil || target != "README.md" {
+			t.Errorf("link -> %q, %v; want %q", target, err, "README.md")
+		}
+	})
+
+	t.Run("zip without top-level directory", func(t *testing.T) {
+		fs := memfs.New()
+		if err := extractArchive(fs, bytes.NewReader(zipData.Bytes()), ArchiveZip); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"repo-1.0.0/README.md", "other.txt"} {
+			if _, err := fs.Lstat(name); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+	})
+
+	t.Run("zip file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "repo.zip")
+		if err := os.WriteFile(path, zipData.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		fs := memfs.New()
+		if err := extractArchive(fs, f, ArchiveZip); err != nil {
+			t.Fatal(err)
+		}
+		if data, err := util.ReadFile(fs, "other.txt"); err != nil || string(data) != "other" {
+			t.Errorf("other.txt = %q, %v; want %q", data, err, "other")
+		}
+	})
+
+	t.Run("top-level directory with an entry of the same name", func(t *testing.T) {
+		var data bytes.Buffer
+		tw := tar.NewWriter(&data)
+		tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "repo/", Mode: 0755})
+		for _, name := range []string{"repo/repo/main.c", "repo/README.md"} {
+			tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: int64(len(name))})
+			tw.Write([]byte(name))
+		}
+		tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "repo/repo/link", Linkname: "../README.md"})
+		tw.Close()
+
+		fs := memfs.New()
+		if err := extractArchive(fs, &data, ArchiveTar); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]string{"repo/main.c": "repo/repo/main.c", "README.md": "repo/README.md", "repo/link": "repo/README.md"}
+		if got := readFiles(t, fs); !reflect.DeepEqual(got, want) {
+			t.Errorf("files = %v; want %v", got, want)
+		}
+	})
+
+	t.Run("tar with hard links", func(t *testing.T) {
+		var data bytes.Buffer
+		gz := gzip.NewWriter(&data)
+		tw := tar.NewWriter(gz)
+		tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "repo/README.md", Mode: 0755, Size: 6})
+		tw.Write([]byte("readme"))
+		tw.WriteHeader(&tar.Header{Typeflag: tar.TypeLink, Name: "repo/docs/README.md", Linkname: "repo/README.md"})
+		tw.Close()
+		gz.Close()
+
+		fs := memfs.New()
+		if err := extractArchive(fs, &data, ArchiveTarGz); err != nil {
+			t.Fatal(err)
+		}
+		if data, err := util.ReadFile(fs, "docs/README.md"); err != nil || string(data) != "readme" {
+			t.Errorf("docs/README.md = %q, %v; want %q", data, err, "readme")
+		}
+		if fi, err := fs.Lstat("docs/README.md"); err != nil || fi.Mode().Perm() != 0755 {
+			t.Errorf("docs/README.md mode = %v, %v; want %v", fi.Mode(), err, os.FileMode(0755))
+		}
+	})
+
+	t.Run("tar with unsafe links", func(t *testing.T) {
+		links := []*tar.Header{
+			{Typeflag: tar.TypeLink, Name: "link", Linkname: "/etc/passwd"},
+			{Typeflag: tar.TypeLink, Name: "link", Linkname: "missing"},
+			{Typeflag: tar.TypeSymlink, Name: "link", Linkname: "/etc/passwd"},
+			{Typeflag: tar.TypeSymlink, Name: "repo/link", Linkname: "../other"},
+		}
+		for _, link := range links {
+			var data bytes.Buffer
+			gz := gzip.NewWriter(&data)
+			tw := tar.NewWriter(gz)
+			tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "repo/README.md", Mode: 0644, Size: 6})
+			tw.Write([]byte("readme"))
+			tw.WriteHeader(link)
+			tw.Close()
+			gz.Close()
+
+			if err := extractArchive(memfs.New(), &data, ArchiveTarGz); err == nil {
+				t.Errorf("%s -> %s: error = nil; want unsafe link error", link.Name, link.Linkname)
+			}
+		}
+	})
+
+	t.Run("unsafe path", func(t *testing.T) {
+		if err := extractArchive(memfs.New(), &unsafe, ArchiveZip); err == nil {
+			t.Errorf("extractArchive() error = nil; want unsafe path error")
+		}
+	})
+}
+
+func TestDegitService_downloadArchive(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte("archive"))
+	}))
+	t.Cleanup(server.Close)
+
+	// The credentials are sent to the host of the remote only, not to the archive endpoint of another host
+	tests := map[string]bool{
+		server.URL + "/starter.tar.gz":        true,
+		"https://example.com/user/repo.git":   false,
+		"git@example.com:user/repo.git":       false,
+		"https://127.0.0.1.example.com/x.zip": false,
+	}
+	for remote, want := range tests {
+		authorization = ""
+		d := NewDegitServiceWithBasicAuth(remote, "user", "secret")
+		body, err := d.downloadArchive(context.Background(), server.URL+"/starter.tar.gz")
+		if err != nil {
+			t.Fatalf("downloadArchive() with the remote %q error: %v", remote, err)
+		}
+		body.Close()
+		if got := authorization != ""; got != want {
+			t.Errorf("downloadArchive() with the remote %q sent credentials = %v; want %v", remote, got, want)
+		}
+	}
+}
@@ -1,9 +1,16 @@
 package degit
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/memfs"
@@ -15,15 +22,45 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/sotvokun/emit/internal/service/log"
+	"github.com/sotvokun/emit/internal/service/progress"
+)
+
+const (
+	PeeledSuffix        = "^{}"
+	MinHashPrefixLength = 4
 )
 
 type WalkFunc func(path string, fs billy.Filesystem) error
 
+// Resolution is the concrete reference resolved from a requested ref.
+type Resolution struct {
+	// Requested is the ref as requested, e.g. "main", "v1.2.0" or "^1.2"
+	Requested string
+	// Reference is the resolved reference to clone, `nil` for archive sources
+	Reference *plumbing.Reference
+	// Commit is the hash of the commit which the reference points to, annotated tags are peeled
+	Commit plumbing.Hash
+	// Selected reports whether the reference was chosen by a selector, e.g. "latest", "^1.2" or "release-*"
+	Selected bool
+}
+
 type DegitService struct {
 	remote     string
 	authMethod transport.AuthMethod
+	retries    int
+	// retryDelay is the first delay between attempts, [RetryInitialDelay] if zero
+	retryDelay time.Duration
+	submodules submoduleOptions
+	lfs        LFSMode
+	mode       Mode
+	filters    filters
+	lockFile   string
+
+	local              *localRepository
+	includeUncommitted bool
 
-	logger log.Logger
+	logger   log.Logger
+	progress progress.Reporter
 }
 
 func NewDegitService(remote string) *DegitService {
@@ -67,96 +104,467 @@ func (d *DegitService) SetLogger(logger log.Logger) {
 	d.logger = logger
 }
 
-func (d *DegitService) Clone(ref string, destDir string, dryMode bool) error {
-	refObj, err := d.getReference(ref)
+// SetProgress sets the reporter which receives the progress of [DegitService.CloneContext].
+func (d *DegitService) SetProgress(reporter progress.Reporter) {
+	d.progress = reporter
+}
+
+// SetRetries sets how many times a network operation is retried after a transient failure.
+func (d *DegitService) SetRetries(retries int) {
+	d.retries = retries
+}
+
+func (d *DegitService) Clone(ref string, dest Destination, dryMode bool) error {
+	return d.CloneContext(context.Background(), ref, dest, dryMode)
+}
+
+// CloneContext clones the repository at the given ref and copies its files into dest, e.g. an [OSDestination].
+// Files written before a failure or a cancellation of ctx are removed.
+func (d *DegitService) CloneContext(ctx context.Context, ref string, dest Destination, dryMode bool) error {
+	resolution, err := d.ResolveContext(ctx, ref)
 	if err != nil {
+		if d.progress != nil {
+			d.progress.Done()
+		}
 		return err
 	}
+	return d.CloneResolutionContext(ctx, resolution, dest, dryMode)
+}
+
+// CloneResolutionContext works like [DegitService.CloneContext] but clones a reference resolved by
+// [DegitService.ResolveContext]. The destination is closed after all the files are copied.
+func (d *DegitService) CloneResolutionContext(ctx context.Context, resolution *Resolution, dest Destination, dryMode bool) error {
+	if d.progress != nil {
+		defer d.progress.Done()
+	}
+
+	if len(d.lockFile) != 0 {
+		if err := ValidateLockFile(d.lockFile); err != nil {
+			return err
+		}
+	}
+
+	walker := NewDestinationWalker(dest)
+	walker.SetLogger(d.logger)
+	walker.SetDryMode(dryMode)
+
+	fs, err := d.fetch(ctx, resolution)
+	if err != nil {
+		return wrapAuth(err)
+	}
 
-	memfs := memfs.New()
-	_, err = git.Clone(memory.NewStorage(), memfs, &git.CloneOptions{
-		URL:               d.remote,
-		ReferenceName:     refObj.Name(),
-		SingleBranch:      true,
-		Depth:             1,
-		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
-		Auth:              d.authMethod,
-		ShallowSubmodules: true,
+	d.phase(progress.PhaseWriting)
+	total := 0
+	err = d.walk(ctx, fs, "", func(string, billy.Filesystem) error {
+		total++
+		return nil
 	})
 	if err != nil {
 		return err
 	}
 
-	walker := NewWalker(destDir)
-	walker.SetLogger(d.logger)
-	walker.SetDryMode(dryMode)
-	if err := d.walk(memfs, "", walker.WalkCopy); err != nil {
+	var lock *Lock
+	if len(d.lockFile) != 0 {
+		lock = d.newLock(resolution)
+	}
+
+	done, written := 0, int64(0)
+	err = d.walk(ctx, fs, "", func(path string, fs billy.Filesystem) error {
+		if err := walker.WalkCopy(path, fs); err != nil {
+			return err
+		}
+		if lock != nil {
+			if err := lock.addFile(path, fs); err != nil {
+				return err
+			}
+		}
+		if d.progress != nil {
+			if fi, err := fs.Lstat(path); err == nil {
+				written += fi.Size()
+			}
+			done++
+			d.progress.Files(done, total, written)
+		}
+		return nil
+	})
+	if err == nil && lock != nil {
+		err = d.writeLock(walker, lock)
+	}
+	if err != nil {
+		if cleanupErr := walker.Cleanup(); cleanupErr != nil {
+			d.log("failed to clean up: %v", cleanupErr)
+		}
 		return err
 	}
 
-	return nil
+	return walker.Close()
 }
 
-// getReference returns the reference with the given ref. The `ref` can be a branch, tag, or commit hash.
-// The HEAD reference will be returned if no ref is provided. `nil` will be returned if the reference is not found.
-func (d *DegitService) getReference(ref string) (*plumbing.Reference, error) {
-	refs, err := d.references()
+// fetch checks out the resolved reference into an in-memory filesystem, removes the files excluded by the filters,
+// and materializes the LFS files.
+func (d *DegitService) fetch(ctx context.Context, resolution *Resolution) (billy.Filesystem, error) {
+	d.phase(progress.PhaseFetching)
+	var sideband io.Writer
+	if d.progress != nil {
+		sideband = progress.NewWriter(d.progress)
+	}
+	var fs billy.Filesystem
+	err := d.retry(ctx, func() error {
+		fs = memfs.New()
+		d.submodules.skipped = nil
+		return d.checkout(ctx, fs, resolution, sideband)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	d.log("found %d references", len(refs))
+	if err := d.filter(ctx, fs); err != nil {
+		return nil, err
+	}
 
-	for _, r := range refs {
-		// Find HEAD reference for no ref provided
-		if len(ref) == 0 {
-			if r.Name() == plumbing.HEAD {
-				return r, nil
-			}
-			continue
+	// An archive source has no LFS server, its pointer files are kept as they are
+	if len(ArchiveFormat(d.remote)) != 0 {
+		return fs, nil
+	}
+	d.phase(progress.PhaseLFS)
+	if err := d.materializeLFS(ctx, fs); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// checkout fetches the resolved reference and checks it out into fs.
+func (d *DegitService) checkout(ctx context.Context, fs billy.Filesystem, resolution *Resolution, sideband io.Writer) error {
+	if format := ArchiveFormat(d.remote); len(format) != 0 {
+		if err := d.fetchArchive(ctx, fs, d.remote, format); err != nil {
+			return err
 		}
+		return d.skipArchiveSubmodules(fs)
+	}
+	if d.mode == ModeTar {
+		u, err := archiveURL(d.remote, resolution.Commit)
+		if err != nil {
+			return err
+		}
+		if err := d.fetchArchive(ctx, fs, u, ArchiveTarGz); err != nil {
+			return err
+		}
+		return d.skipArchiveSubmodules(fs)
+	}
 
-		// Find reference by hash
-		if strings.HasPrefix(r.Hash().String(), ref) {
-			return r, nil
+	local, err := d.openLocal()
+	if err != nil {
+		return err
+	}
+	if d.includeUncommitted && local == nil {
+		return fmt.Errorf("uncommitted changes can only be included from a local working tree")
+	}
+	if local != nil {
+		var skipped []string
+		if d.includeUncommitted {
+			d.log("copy working tree: %s", local.worktree)
+			skipped, err = local.copyWorktree(fs, d.submoduleSelected)
+		} else {
+			skipped, err = local.checkout(fs, resolution.Commit)
+		}
+		for _, path := range skipped {
+			d.log("skip submodule: %s", path)
 		}
+		d.submodules.skipped = append(d.submodules.skipped, skipped...)
+		return err
+	}
 
-		// Find reference by branch name
-		if r.Name().IsBranch() && r.Name() == plumbing.NewBranchReferenceName(ref) {
-			return r, nil
+	if resolution.Reference == nil {
+		// A commit without a reference, e.g. the commit recorded in a lock file, is fetched by its hash.
+		// The history of all branches and tags is fetched instead if the remote does not allow it.
+		refspec := config.RefSpec(fmt.Sprintf("+%s:refs/emit/commit", resolution.Commit))
+		err := d.fetchCommit(ctx, fs, []config.RefSpec{refspec}, 1, resolution.Commit, sideband)
+		if !errors.Is(err, git.ErrExactSHA1NotSupported) {
+			return err
 		}
+		d.log("fetching a commit is not supported by the remote, fetch the history of all branches and tags")
+		refspecs := []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"}
+		return d.fetchCommit(ctx, fs, refspecs, 0, resolution.Commit, sideband)
+	}
 
-		// Find reference by tag name
-		if r.Name().IsTag() && r.Name() == plumbing.NewTagReferenceName(ref) {
-			return r, nil
+	name := resolution.Reference.Name()
+	if name == plumbing.HEAD || name.IsBranch() || name.IsTag() {
+		repo, err := git.CloneContext(ctx, memory.NewStorage(), fs, &git.CloneOptions{
+			URL:           d.remote,
+			ReferenceName: name,
+			SingleBranch:  true,
+			Depth:         1,
+			Auth:          d.authMethod,
+			Progress:      sideband,
+		})
+		if err != nil {
+			return err
 		}
+		return d.updateSubmodules(ctx, repo, "")
 	}
 
-	return nil, fmt.Errorf("reference '%s' not found", ref)
+	// git.Clone only supports branches and tags, other references (e.g. "refs/pull/123/head") are fetched
+	// with an explicit refspec and checked out by the commit hash.
+	refspec := config.RefSpec(fmt.Sprintf("+%s:%s", name, name))
+	return d.fetchCommit(ctx, fs, []config.RefSpec{refspec}, 1, resolution.Commit, sideband)
 }
 
-// references returns the references of the remote repository
-func (d *DegitService) references() ([]*plumbing.Reference, error) {
+// fetchCommit fetches the refspecs into an empty repository and checks out the commit into fs,
+// the whole history is fetched if depth is 0.
+func (d *DegitService) fetchCommit(ctx context.Context, fs billy.Filesystem, refspecs []config.RefSpec, depth int, commit plumbing.Hash, sideband io.Writer) error {
+	repo, err := git.Init(memory.NewStorage(), fs)
+	if err != nil {
+		return err
+	}
+	remote, err := repo.CreateRemote(&config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{d.remote},
+	})
+	if err != nil {
+		return err
+	}
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: refspecs,
+		Depth:    depth,
+		Auth:     d.authMethod,
+		Progress: sideband,
+	})
+	if err != nil {
+		return err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if err := worktree.Checkout(&git.CheckoutOptions{Hash: commit, Force: true}); err != nil {
+		return err
+	}
+	return d.updateSubmodules(ctx, repo, "")
+}
+
+// ListContext returns the references of the remote repository.
+// Each annotated tag is followed by a peeled reference, which has the [PeeledSuffix] and points to the tagged commit.
+func (d *DegitService) ListContext(ctx context.Context) ([]*plumbing.Reference, error) {
+	if len(ArchiveFormat(d.remote)) != 0 {
+		return nil, fmt.Errorf("archive sources do not have references")
+	}
+
+	local, err := d.openLocal()
+	if err != nil {
+		return nil, err
+	}
+	if local != nil {
+		return local.references()
+	}
+
 	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
 		Name: "origin",
 		URLs: []string{d.remote},
 	})
-	return remote.List(&git.ListOptions{
-		Auth: d.authMethod,
+
+	var refs []*plumbing.Reference
+	err = d.retry(ctx, func() error {
+		var err error
+		refs, err = remote.ListContext(ctx, &git.ListOptions{
+			Auth:          d.authMethod,
+			PeelingOption: git.AppendPeeled,
+		})
+		return err
 	})
+	return refs, wrapAuth(err)
+}
+
+// ResolveContext resolves the ref against the references of the remote repository. The `ref` can be a branch,
+// tag, commit hash, or a selector choosing a tag (see [selectTag]). The HEAD reference is resolved if no ref is provided.
+// Archive sources are not resolved, since they have no references.
+func (d *DegitService) ResolveContext(ctx context.Context, ref string) (*Resolution, error) {
+	if len(ArchiveFormat(d.remote)) != 0 {
+		if len(ref) != 0 {
+			return nil, fmt.Errorf("archive sources do not have references")
+		}
+		return &Resolution{}, nil
+	}
+
+	d.phase(progress.PhaseListing)
+	refs, err := d.ListContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	d.log("found %d references", len(refs))
+
+	resolution := &Resolution{Requested: ref}
+	resolution.Reference, err = getReference(refs, ref)
+	if err != nil {
+		return nil, err
+	}
+	if resolution.Reference == nil {
+		tag, selector, err := selectTag(refs, ref)
+		if !selector {
+			name := ref
+			if len(name) == 0 {
+				name = plumbing.HEAD.String()
+			}
+			return nil, fmt.Errorf("%w: '%s'", ErrReferenceNotFound, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		resolution.Reference = tag
+		resolution.Selected = true
+	}
+	resolution.Commit = peel(refs, resolution.Reference)
+
+	d.log("resolved '%s' to %s (%s)", ref, resolution.Reference.Name(), resolution.Commit)
+	return resolution, nil
 }
 
-// walk walks the filesystem and calls the given function for each non-directory entry
-func (d *DegitService) walk(fs billy.Filesystem, root string, fn WalkFunc) error {
+// getReference returns the reference with the given ref, `nil` will be returned if the reference is not found.
+// The HEAD reference will be returned if no ref is provided. Otherwise the first of the following matches is returned:
+//  1. the full reference name, e.g. "refs/heads/main", "refs/tags/v1.0.0" or "refs/pull/123/head"
+//  2. the branch or tag name, e.g. "main" or "v1.0.0"
+//  3. the commit hash, or its prefix of at least [MinHashPrefixLength] characters
+//
+// An [*AmbiguousReferenceError] is returned if the name is both a branch and a tag, or if the commit hash prefix
+// matches more than one commit.
+func getReference(refs []*plumbing.Reference, ref string) (*plumbing.Reference, error) {
+	if len(ref) == 0 {
+		ref = plumbing.HEAD.String()
+	}
+
+	if r := findReference(refs, plumbing.ReferenceName(ref)); r != nil {
+		return r, nil
+	}
+	branch := findReference(refs, plumbing.NewBranchReferenceName(ref))
+	tag := findReference(refs, plumbing.NewTagReferenceName(ref))
+	switch {
+	case branch != nil && tag != nil:
+		return nil, &AmbiguousReferenceError{Ref: ref, Candidates: []string{
+			fmt.Sprintf("%s (%s)", branch.Name(), peel(refs, branch)),
+			fmt.Sprintf("%s (%s)", tag.Name(), peel(refs, tag)),
+		}}
+	case branch != nil:
+		return branch, nil
+	case tag != nil:
+		return tag, nil
+	}
+
+	if !isHashPrefix(ref) {
+		return nil, nil
+	}
+
+	var candidates []*plumbing.Reference
+	commits := make(map[plumbing.Hash]bool)
+	for _, r := range refs {
+		if IsPeeled(r.Name()) {
+			continue
+		}
+		commit := peel(refs, r)
+		if strings.HasPrefix(r.Hash().String(), ref) || strings.HasPrefix(commit.String(), ref) {
+			candidates = append(candidates, r)
+			commits[commit] = true
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	slices.SortFunc(candidates, func(a, b *plumbing.Reference) int {
+		if c := cmp.Compare(referencePriority(a.Name()), referencePriority(b.Name())); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name().String(), b.Name().String())
+	})
+	if len(commits) > 1 {
+		err := &AmbiguousReferenceError{Ref: ref}
+		for _, r := range candidates {
+			err.Candidates = append(err.Candidates, fmt.Sprintf("%s (%s)", r.Name(), peel(refs, r)))
+		}
+		return nil, err
+	}
+	return candidates[0], nil
+}
+
+// findReference returns the reference with the full name, the peeled tags are not matched.
+func findReference(refs []*plumbing.Reference, name plumbing.ReferenceName) *plumbing.Reference {
+	for _, r := range refs {
+		if r.Name() == name && !IsPeeled(r.Name()) {
+			return r
+		}
+	}
+	return nil
+}
+
+// isHashPrefix reports whether the ref looks like a commit hash or an abbreviation of it.
+func isHashPrefix(ref string) bool {
+	if len(ref) < MinHashPrefixLength || len(ref) > len(plumbing.ZeroHash.String()) {
+		return false
+	}
+	for _, c := range ref {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// referencePriority orders the references pointing to the same commit: HEAD, branches, tags, and others.
+func referencePriority(name plumbing.ReferenceName) int {
+	switch {
+	case name == plumbing.HEAD:
+		return 0
+	case name.IsBranch():
+		return 1
+	case name.IsTag():
+		return 2
+	}
+	return 3
+}
+
+// peel returns the commit hash which the reference points to, following symbolic references and annotated tags.
+func peel(refs []*plumbing.Reference, ref *plumbing.Reference) plumbing.Hash {
+	// Bounded by the number of references in case of a symbolic reference cycle
+	for range refs {
+		if ref.Type() != plumbing.SymbolicReference {
+			break
+		}
+		for _, r := range refs {
+			if r.Name() == ref.Target() {
+				ref = r
+				break
+			}
+		}
+	}
+
+	peeledName := plumbing.ReferenceName(ref.Name().String() + PeeledSuffix)
+	for _, r := range refs {
+		if r.Name() == peeledName {
+			return r.Hash()
+		}
+	}
+	return ref.Hash()
+}
+
+// walk walks the filesystem in lexical order and calls the given function for each non-directory entry.
+// The walk stops as soon as ctx is done.
+func (d *DegitService) walk(ctx context.Context, fs billy.Filesystem, root string, fn WalkFunc) error {
 	entries, err := fs.ReadDir(root)
 	if err != nil {
 		return err
 	}
+	slices.SortFunc(entries, func(a, b os.FileInfo) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
 
 	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		fullPath := filepath.Join(root, entry.Name())
 		if entry.IsDir() {
-			if err := d.walk(fs, fullPath, fn); err != nil {
+			if err := d.walk(ctx, fs, fullPath, fn); err != nil {
 				return err
 			}
 			continue
@@ -168,6 +576,18 @@ func (d *DegitService) walk(fs billy.Filesystem, root string, fn WalkFunc) error
 	return nil
 }
 
+// IsPeeled reports whether the reference name is a peeled annotated tag returned by [DegitService.ListContext].
+func IsPeeled(name plumbing.ReferenceName) bool {
+	return strings.HasSuffix(name.String(), PeeledSuffix)
+}
+
+func (d *DegitService) phase(name string) {
+	if d.progress == nil {
+		return
+	}
+	d.progress.Phase(name)
+}
+
 func (d *DegitService) log(msg string, a ...any) {
 	if d.logger == nil {
 		return
@@ -0,0 +1,178 @@
+package degit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// newTestRepository creates a working tree with a commit of the files on the "main" branch.
+func newTestRepository(t *testing.T, files map[string]string) (string, plumbing.Hash) {
+	t.Helper()
+
+	dir := t.TempDir()
+	repo, err := git.PlainInitWithOptions(dir, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := worktree.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signature := &object.Signature{Name: "emit", Email: "emit@example.com", When: time.Unix(0, 0)}
+	commit, err := worktree.Commit("initial", &git.CommitOptions{Author: signature})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, commit
+}
+
+func TestGetReference(t *testing.T) {
+	refs := []*plumbing.Reference{
+		plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/main"),
+		plumbing.NewReferenceFromStrings("refs/heads/main", "abc1000000000000000000000000000000000000"),
+		plumbing.NewReferenceFromStrings("refs/heads/abc1", "def0000000000000000000000000000000000000"),
+		plumbing.NewReferenceFromStrings("refs/heads/v1.0.0", "fed0000000000000000000000000000000000000"),
+		plumbing.NewReferenceFromStrings("refs/tags/v1.0.0", "abc2000000000000000000000000000000000000"),
+		plumbing.NewReferenceFromStrings("refs/tags/v2.0.0", "1230000000000000000000000000000000000000"),
+		plumbing.NewReferenceFromStrings("refs/tags/v2.0.0^{}", "4560000000000000000000000000000000000000"),
+		plumbing.NewReferenceFromStrings("refs/pull/123/head", "7890000000000000000000000000000000000000"),
+		plumbing.NewReferenceFromStrings("refs/tags/v3.0.0", "abcd100000000000000000000000000000000000"),
+		plumbing.NewReferenceFromStrings("refs/tags/v3.0.1", "abcd200000000000000000000000000000000000"),
+	}
+
+	type testcase struct {
+		ref           string
+		want          plumbing.ReferenceName
+		wantAmbiguous bool
+	}
+
+	tests := []testcase{
+		{"", plumbing.HEAD, false},
+		{"main", "refs/heads/main", false},
+		{"abc1", "refs/heads/abc1", false},
+		{"refs/heads/v1.0.0", "refs/heads/v1.0.0", false},
+		{"refs/tags/v1.0.0", "refs/tags/v1.0.0", false},
+		{"v2.0.0", "refs/tags/v2.0.0", false},
+		{"refs/pull/123/head", "refs/pull/123/head", false},
+		{"abc10", plumbing.HEAD, false},
+		{"abc2", "refs/tags/v1.0.0", false},
+		{"4560", "refs/tags/v2.0.0", false},
+		{"7890", "refs/pull/123/head", false},
+		{"abc", "", false},
+		{"abc0", "", false},
+		{"pull/123/head", "", false},
+		{"v2.0.0^{}", "", false},
+
+		{"abcd", "", true},
+		{"v1.0.0", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ref, func(t *testing.T) {
+			got, err := getReference(refs, test.ref)
+
+			var ambiguous *AmbiguousReferenceError
+			if errors.As(err, &ambiguous) != test.wantAmbiguous {
+				t.Fatalf("getReference(%q) error = %v; wantAmbiguous %v", test.ref, err, test.wantAmbiguous)
+			}
+			if test.wantAmbiguous {
+				if len(ambiguous.Candidates) != 2 {
+					t.Errorf("getReference(%q) candidates = %v; want 2", test.ref, ambiguous.Candidates)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getReference(%q) error: %v", test.ref, err)
+			}
+
+			gotName := plumbing.ReferenceName("")
+			if got != nil {
+				gotName = got.Name()
+			}
+			if gotName != test.want {
+				t.Errorf("getReference(%q) = %q; want %q", test.ref, gotName, test.want)
+			}
+		})
+	}
+}
+
+func TestSelectTag(t *testing.T) {
+	hash := "1230000000000000000000000000000000000000"
+	refs := []*plumbing.Reference{
+		plumbing.NewReferenceFromStrings("refs/heads/main", hash),
+		plumbing.NewReferenceFromStrings("refs/heads/v9.0.0", hash),
+		plumbing.NewReferenceFromStrings("refs/tags/v1.2.0", hash),
+		plumbing.NewReferenceFromStrings("refs/tags/v1.10.0", hash),
+		plumbing.NewReferenceFromStrings("refs/tags/v1.10.0^{}", hash),
+		plumbing.NewReferenceFromStrings("refs/tags/2.0.0", hash),
+		plumbing.NewReferenceFromStrings("refs/tags/v2.1.0-rc.1", hash),
+		plumbing.NewReferenceFromStrings("refs/tags/release-1.9.0", hash),
+		plumbing.NewReferenceFromStrings("refs/tags/release-1.10.0", hash),
+		plumbing.NewReferenceFromStrings("refs/tags/nightly", hash),
+	}
+
+	type testcase struct {
+		ref      string
+		want     plumbing.ReferenceName
+		selector bool
+		wantErr  bool
+	}
+
+	tests := []testcase{
+		{"latest", "refs/tags/2.0.0", true, false},
+		{"latest-prerelease", "refs/tags/v2.1.0-rc.1", true, false},
+		{"^1.2", "refs/tags/v1.10.0", true, false},
+		{"~1.2.0", "refs/tags/v1.2.0", true, false},
+		{">=2.1.0-rc.0", "refs/tags/v2.1.0-rc.1", true, false},
+		{"release-*", "refs/tags/release-1.10.0", true, false},
+		{"^3", "", true, true},
+		{"foo-*", "", true, true},
+		{"main", "", false, false},
+		{"nightly", "", false, false},
+		{"1.x", "refs/tags/v1.10.0", true, false},
+		// A bare wildcard or an empty ref matches every version, but it is not taken as a range
+		{"x", "", false, false},
+		{"X", "", false, false},
+		{"x || X", "", false, false},
+		{"", "", false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ref, func(t *testing.T) {
+			got, selector, err := selectTag(refs, test.ref)
+			if selector != test.selector || (err != nil) != test.wantErr {
+				t.Fatalf("selectTag(%q) = selector %v, error %v; want selector %v, wantErr %v",
+					test.ref, selector, err, test.selector, test.wantErr)
+			}
+
+			gotName := plumbing.ReferenceName("")
+			if got != nil {
+				gotName = got.Name()
+			}
+			if gotName != test.want {
+				t.Errorf("selectTag(%q) = %q; want %q", test.ref, gotName, test.want)
+			}
+		})
+	}
+}
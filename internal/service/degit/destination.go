@@ -0,0 +1,378 @@
+package degit
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ArchiveModTime is the modification time of all the entries written to an archive, so that the archive only
+// depends on the files. It is the earliest time representable in a zip archive.
+var ArchiveModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Destination receives the files copied by a [Walker]. The paths are relative and use the OS separator.
+// The implementations are [OSDestination], [FilesystemDestination] and [ArchiveDestination].
+type Destination interface {
+	// Check returns an error if the file cannot be written, e.g. [ErrDestinationExists]. It is called in the dry
+	// mode too.
+	Check(path string) error
+	// WriteFile writes the file of the size from r, the parent directories are created as needed.
+	WriteFile(path string, perm os.FileMode, size int64, r io.Reader) error
+	// Symlink creates a symbolic link to the target, the parent directories are created as needed.
+	Symlink(path string, target string) error
+	// Close completes the destination after all the files are written.
+	Close() error
+	// Cleanup removes what has been written, it is called when the copy fails.
+	Cleanup() error
+}
+
+// OSDestination writes the files into a directory of the OS filesystem, existing files are not overwritten.
+type OSDestination struct {
+	root string
+
+	// created records the files and directories created, in creation order
+	created []string
+}
+
+func NewOSDestination(root string) *OSDestination {
+	return &OSDestination{root: root}
+}
+
+// Path returns the path of the file on the OS filesystem.
+func (d *OSDestination) Path(path string) string {
+	return filepath.Join(d.root, path)
+}
+
+func (d *OSDestination) Check(path string) error {
+	if _, err := os.Lstat(d.Path(path)); err == nil {
+		return ErrDestinationExists
+	}
+	return nil
+}
+
+func (d *OSDestination) WriteFile(path string, perm os.FileMode, size int64, r io.Reader) error {
+	fullPath := d.Path(path)
+	if err := d.mkdirAll(filepath.Dir(fullPath)); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	d.created = append(d.created, fullPath)
+
+	_, err = io.Copy(f, r)
+	return err
+}
+
+func (d *OSDestination) Symlink(path string, target string) error {
+	fullPath := d.Path(path)
+	if err := d.mkdirAll(filepath.Dir(fullPath)); err != nil {
+		return err
+	}
+
+	if err := os.Symlink(target, fullPath); err != nil {
+		return err
+	}
+	d.created = append(d.created, fullPath)
+	return nil
+}
+
+func (d *OSDestination) Close() error {
+	return nil
+}
+
+// Cleanup removes the files and directories created, most recent first. Directories that are not empty are kept.
+func (d *OSDestination) Cleanup() error {
+	var errs []error
+	for i := len(d.created) - 1; i >= 0; i-- {
+		path := d.created[i]
+		fi, err := os.Lstat(path)
+		if err != nil {
+			continue
+		}
+
+		if err := os.Remove(path); err != nil && !fi.IsDir() {
+			errs = append(errs, err)
+		}
+	}
+	d.created = nil
+	return errors.Join(errs...)
+}
+
+// mkdirAll works like [os.MkdirAll] but records the directories it creates.
+func (d *OSDestination) mkdirAll(dir string) error {
+	var missing []string
+	for p := dir; ; p = filepath.Dir(p) {
+		if _, err := os.Lstat(p); err == nil {
+			break
+		}
+		missing = append(missing, p)
+		if filepath.Dir(p) == p {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		d.created = append(d.created, missing[i])
+	}
+	return nil
+}
+
+// ArchiveDestination writes the files as a tar or zip archive to a stream. The archive is reproducible:
+// the entries are written in the walk order with fixed timestamps and normalized modes.
+type ArchiveDestination struct {
+	tar        *tar.Writer
+	zip        *zip.Writer
+	compressor io.WriteCloser
+
+	// dirs records the directories written, the parent directories are written before their first entry
+	dirs map[string]bool
+}
+
+// NewArchiveDestination creates a destination writing an archive of the format to w, see [ArchiveFormat].
+func NewArchiveDestination(w io.Writer, format string) (*ArchiveDestination, error) {
+	a := &ArchiveDestination{dirs: make(map[string]bool)}
+	switch format {
+	case ArchiveTar:
+		a.tar = tar.NewWriter(w)
+	case ArchiveTarGz:
+		a.compressor = gzip.NewWriter(w)
+		a.tar = tar.NewWriter(a.compressor)
+	case ArchiveTarZst:
+		encoder, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		a.compressor = encoder
+		a.tar = tar.NewWriter(a.compressor)
+	case ArchiveZip:
+		a.zip = zip.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported archive format '%s'", format)
+	}
+	return a, nil
+}
+
+func (a *ArchiveDestination) Check(string) error {
+	return nil
+}
+
+func (a *ArchiveDestination) WriteFile(p string, perm os.FileMode, size int64, r io.Reader) error {
+	name := filepath.ToSlash(p)
+	if err := a.mkdirAll(path.Dir(name)); err != nil {
+		return err
+	}
+
+	// Only the executable bit is kept like git does
+	mode := os.FileMode(0644)
+	if perm&0111 != 0 {
+		mode = 0755
+	}
+
+	if a.zip != nil {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: ArchiveModTime}
+		header.SetMode(mode)
+		w, err := a.zip.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, r)
+		return err
+	}
+
+	err := a.tar.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(mode),
+		Size:     size,
+		ModTime:  ArchiveModTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(a.tar, r)
+	return err
+}
+
+func (a *ArchiveDestination) Symlink(p string, target string) error {
+	name := filepath.ToSlash(p)
+	if err := a.mkdirAll(path.Dir(name)); err != nil {
+		return err
+	}
+
+	if a.zip != nil {
+		header := &zip.FileHeader{Name: name, Method: zip.Store, Modified: ArchiveModTime}
+		header.SetMode(os.ModeSymlink | 0777)
+		w, err := a.zip.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, target)
+		return err
+	}
+
+	return a.tar.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeSymlink,
+		Name:     name,
+		Linkname: target,
+		Mode:     0777,
+		ModTime:  ArchiveModTime,
+	})
+}
+
+func (a *ArchiveDestination) mkdirAll(dir string) error {
+	if dir == "." || a.dirs[dir] {
+		return nil
+	}
+	if err := a.mkdirAll(path.Dir(dir)); err != nil {
+		return err
+	}
+	a.dirs[dir] = true
+
+	if a.zip != nil {
+		header := &zip.FileHeader{Name: dir + "/", Modified: ArchiveModTime}
+		header.SetMode(os.ModeDir | 0755)
+		_, err := a.zip.CreateHeader(header)
+		return err
+	}
+	return a.tar.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     dir + "/",
+		Mode:     0755,
+		ModTime:  ArchiveModTime,
+	})
+}
+
+func (a *ArchiveDestination) Close() error {
+	if a.zip != nil {
+		return a.zip.Close()
+	}
+	if err := a.tar.Close(); err != nil {
+		return err
+	}
+	if a.compressor != nil {
+		return a.compressor.Close()
+	}
+	return nil
+}
+
+// Cleanup does nothing, the archive is written to a stream owned by the caller.
+func (a *ArchiveDestination) Cleanup() error {
+	return nil
+}
+
+// FilesystemDestination writes the files into a [billy.Filesystem], e.g. an in-memory filesystem created by
+// [memfs.New]. Existing files are not overwritten.
+type FilesystemDestination struct {
+	fs billy.Filesystem
+
+	// created records the files and directories created, in creation order
+	created []string
+}
+
+func NewFilesystemDestination(fs billy.Filesystem) *FilesystemDestination {
+	return &FilesystemDestination{fs: fs}
+}
+
+// NewMemoryDestination creates a destination writing into a new in-memory filesystem.
+func NewMemoryDestination() *FilesystemDestination {
+	return NewFilesystemDestination(memfs.New())
+}
+
+// Filesystem returns the filesystem which the files are written into.
+func (f *FilesystemDestination) Filesystem() billy.Filesystem {
+	return f.fs
+}
+
+func (f *FilesystemDestination) Check(path string) error {
+	if _, err := f.fs.Lstat(path); err == nil {
+		return ErrDestinationExists
+	}
+	return nil
+}
+
+func (f *FilesystemDestination) WriteFile(path string, perm os.FileMode, size int64, r io.Reader) error {
+	if err := f.mkdirAll(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	file, err := f.fs.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	f.created = append(f.created, path)
+
+	_, err = io.Copy(file, r)
+	return err
+}
+
+func (f *FilesystemDestination) Symlink(path string, target string) error {
+	if err := f.mkdirAll(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	if err := f.fs.Symlink(target, path); err != nil {
+		return err
+	}
+	f.created = append(f.created, path)
+	return nil
+}
+
+func (f *FilesystemDestination) Close() error {
+	return nil
+}
+
+// Cleanup removes the files and directories created, most recent first. Directories that are not empty are kept.
+func (f *FilesystemDestination) Cleanup() error {
+	var errs []error
+	for i := len(f.created) - 1; i >= 0; i-- {
+		path := f.created[i]
+		fi, err := f.fs.Lstat(path)
+		if err != nil {
+			continue
+		}
+
+		if err := f.fs.Remove(path); err != nil && !fi.IsDir() {
+			errs = append(errs, err)
+		}
+	}
+	f.created = nil
+	return errors.Join(errs...)
+}
+
+// mkdirAll works like [billy.Dir.MkdirAll] but records the directories it creates.
+func (f *FilesystemDestination) mkdirAll(dir string) error {
+	var missing []string
+	for p := dir; p != "." && p != string(filepath.Separator); p = filepath.Dir(p) {
+		if _, err := f.fs.Lstat(p); err == nil {
+			break
+		}
+		missing = append(missing, p)
+	}
+
+	if err := f.fs.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		f.created = append(f.created, missing[i])
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package degit
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestArchiveWalker(t *testing.T) {
+	fs := memfs.New()
+	util.WriteFile(fs, "README.md", []byte("readme"), 0644)
+	util.WriteFile(fs, "bin/run.sh", []byte("#!/bin/sh"), 0700)
+	fs.Symlink("README.md", "link")
+
+	write := func(format string) []byte {
+		var buf bytes.Buffer
+		dest, err := NewArchiveDestination(&buf, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker := NewDestinationWalker(dest)
+		err = (&DegitService{}).walk(context.Background(), fs, "", func(path string, fs billy.Filesystem) error {
+			return walker.WalkCopy(path, fs)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := walker.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	for _, format := range []string{ArchiveTar, ArchiveTarGz, ArchiveTarZst, ArchiveZip} {
+		t.Run(format, func(t *testing.T) {
+			data := write(format)
+			if !bytes.Equal(data, write(format)) {
+				t.Errorf("archive is not reproducible")
+			}
+
+			extracted := memfs.New()
+			if err := extractArchive(extracted, bytes.NewReader(data), format); err != nil {
+				t.Fatal(err)
+			}
+			if fi, err := extracted.Lstat("bin/run.sh"); err != nil || fi.Mode() != 0755 {
+				t.Errorf("bin/run.sh mode = %v, %v; want %v", fi.Mode(), err, os.FileMode(0755))
+			}
+			if target, err := extracted.Readlink("link"); err != nil || target != "README.md" {
+				t.Errorf("link -> %q, %v; want %q", target, err, "README.md")
+			}
+			var names []string
+			for name := range readFiles(t, extracted) {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			want := []string{"README.md", "bin/run.sh", "link"}
+			if !slices.Equal(names, want) {
+				t.Errorf("entries = %v; want %v", names, want)
+			}
+		})
+	}
+}
+
+func TestFilesystemDestination(t *testing.T) {
+	dest := NewMemoryDestination()
+	util.WriteFile(dest.Filesystem(), "existing.txt", []byte("existing"), 0644)
+
+	if err := dest.WriteFile("a/b/c.txt", 0644, 1, bytes.NewReader([]byte("c"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := dest.Symlink("a/link", "b/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dest.Check("a/b/c.txt"); err == nil {
+		t.Errorf("Check(%q) error = nil; want exists", "a/b/c.txt")
+	}
+
+	if err := dest.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dest.Filesystem().Lstat("a"); err == nil {
+		t.Errorf("a: exists after cleanup")
+	}
+	if _, err := dest.Filesystem().Lstat("existing.txt"); err != nil {
+		t.Errorf("existing.txt: %v", err)
+	}
+}
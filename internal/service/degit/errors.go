@@ -0,0 +1,74 @@
+package degit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+var (
+	// ErrReferenceNotFound is returned when no reference matches the requested ref.
+	ErrReferenceNotFound = errors.New("reference not found")
+	// ErrDestinationExists is returned when a file to write already exists in the destination, it matches
+	// [os.ErrExist] too.
+	ErrDestinationExists = fmt.Errorf("destination %w", os.ErrExist)
+	// ErrAuth is returned when the remote rejects the authentication, or requires one.
+	ErrAuth = errors.New("authentication failed")
+)
+
+// AmbiguousReferenceError is returned when a ref matches both a branch and a tag, or more than one commit.
+type AmbiguousReferenceError struct {
+	Ref        string
+	Candidates []string
+}
+
+func (e *AmbiguousReferenceError) Error() string {
+	return fmt.Sprintf("reference '%s' is ambiguous, use the full reference name or a longer hash, candidates:\n    %s",
+		e.Ref, strings.Join(e.Candidates, "\n    "))
+}
+
+// statusError is returned when an HTTP server, e.g. an LFS server or an archive host, responds with an unexpected
+// status code.
+type statusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected requesting %q status code: %d", e.URL, e.StatusCode)
+}
+
+// authError marks an error of the transport as [ErrAuth], the original error is kept.
+type authError struct {
+	err error
+}
+
+func (e *authError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrAuth, e.err)
+}
+
+func (e *authError) Is(target error) bool {
+	return target == ErrAuth
+}
+
+func (e *authError) Unwrap() error {
+	return e.err
+}
+
+// wrapAuth wraps the authentication errors of the transports with [authError].
+func wrapAuth(err error) error {
+	if err == nil || errors.Is(err, ErrAuth) {
+		return err
+	}
+	if errors.Is(err, transport.ErrAuthenticationRequired) ||
+		errors.Is(err, transport.ErrAuthorizationFailed) ||
+		errors.Is(err, transport.ErrInvalidAuthMethod) ||
+		// The SSH transport does not have a typed error for the rejected keys
+		strings.Contains(err.Error(), "unable to authenticate") {
+		return &authError{err: err}
+	}
+	return err
+}
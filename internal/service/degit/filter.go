@@ -0,0 +1,77 @@
+package degit
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// filters selects the files to copy by the gitignore-style patterns.
+type filters struct {
+	include gitignore.Matcher
+	exclude gitignore.Matcher
+
+	includePatterns []string
+	excludePatterns []string
+}
+
+// SetFilters sets the gitignore-style patterns selecting the files to copy, e.g. "src/", "*.md" or "!README.md".
+// A file is copied if it matches the include patterns, or there is none, and it does not match the exclude patterns.
+func (d *DegitService) SetFilters(include []string, exclude []string) {
+	d.filters = filters{
+		include: newMatcher(include),
+		exclude: newMatcher(exclude),
+
+		includePatterns: include,
+		excludePatterns: exclude,
+	}
+}
+
+func newMatcher(patterns []string) gitignore.Matcher {
+	if len(patterns) == 0 {
+		return nil
+	}
+	var ps []gitignore.Pattern
+	for _, p := range patterns {
+		ps = append(ps, gitignore.ParsePattern(p, nil))
+	}
+	return gitignore.NewMatcher(ps)
+}
+
+// selected reports whether the file at the path is copied, the path uses the OS separator.
+func (f filters) selected(path string) bool {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	if f.include != nil && !f.include.Match(parts, false) {
+		return false
+	}
+	return f.exclude == nil || !f.exclude.Match(parts, false)
+}
+
+// filter removes the files which are not selected by the filters from fs.
+func (d *DegitService) filter(ctx context.Context, fs billy.Filesystem) error {
+	if d.filters.include == nil && d.filters.exclude == nil {
+		return nil
+	}
+
+	var excluded []string
+	err := d.walk(ctx, fs, "", func(path string, fs billy.Filesystem) error {
+		if !d.filters.selected(path) {
+			excluded = append(excluded, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, path := range excluded {
+		d.log("exclude: %s", path)
+		if err := fs.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
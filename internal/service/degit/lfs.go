@@ -0,0 +1,428 @@
+package degit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	formatconfig "github.com/go-git/go-git/v5/plumbing/format/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+type LFSMode int
+
+const (
+	// LFSFetch replaces the LFS pointer files with the objects downloaded from the LFS server, it is the default mode
+	LFSFetch LFSMode = iota
+	// LFSPointer keeps the LFS pointer files as they are
+	LFSPointer
+	// LFSSkip removes the LFS pointer files
+	LFSSkip
+)
+
+const (
+	LFSPointerMaxSize = 1024
+	LFSBatchSize      = 100
+	LFSMediaType      = "application/vnd.git-lfs+json"
+
+	lfsPointerVersion = "version https://git-lfs.github.com/spec/v1"
+)
+
+// ParseLFSMode parses the mode name: "fetch", "pointer" or "skip".
+func ParseLFSMode(name string) (LFSMode, error) {
+	switch name {
+	case "fetch":
+		return LFSFetch, nil
+	case "pointer":
+		return LFSPointer, nil
+	case "skip":
+		return LFSSkip, nil
+	}
+	return 0, fmt.Errorf("invalid lfs mode '%s'", name)
+}
+
+// String returns the name of the mode, see [ParseLFSMode].
+func (m LFSMode) String() string {
+	switch m {
+	case LFSPointer:
+		return "pointer"
+	case LFSSkip:
+		return "skip"
+	}
+	return "fetch"
+}
+
+// SetLFS sets how the Git LFS pointer files are handled.
+func (d *DegitService) SetLFS(mode LFSMode) {
+	d.lfs = mode
+}
+
+// lfsPointer is the content of a Git LFS pointer file.
+type lfsPointer struct {
+	OID  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// parseLFSPointer parses the content of a Git LFS pointer file, false is returned if it is not a pointer.
+func parseLFSPointer(data []byte) (lfsPointer, bool) {
+	var p lfsPointer
+	if len(data) > LFSPointerMaxSize || !bytes.HasPrefix(data, []byte(lfsPointerVersion+"\n")) {
+		return p, false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		switch key {
+		case "oid":
+			oid, ok := strings.CutPrefix(value, "sha256:")
+			if !ok || len(oid) != sha256.Size*2 {
+				return p, false
+			}
+			p.OID = oid
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size < 0 {
+				return p, false
+			}
+			p.Size = size
+		}
+	}
+	return p, len(p.OID) != 0
+}
+
+// lfsClient downloads objects with the basic transfer of the Git LFS batch API.
+type lfsClient struct {
+	endpoint string
+	// origin is the LFS endpoint derived from the clone remote, the credentials are only sent to its scheme and host
+	origin string
+	auth   transport.AuthMethod
+	client *nethttp.Client
+}
+
+type lfsBatchRequest struct {
+	Operation string       `json:"operation"`
+	Transfers []string     `json:"transfers"`
+	Objects   []lfsPointer `json:"objects"`
+}
+
+type lfsBatchResponse struct {
+	Objects []struct {
+		lfsPointer
+		Actions struct {
+			Download *lfsAction `json:"download"`
+		} `json:"actions"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"objects"`
+}
+
+type lfsAction struct {
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header"`
+}
+
+// lfsConfigURL returns the "lfs.url" option of the ".lfsconfig" file in fs, which has precedence over the endpoint
+// derived from the remote URL. An empty string is returned if it is not set.
+func lfsConfigURL(fs billy.Filesystem) string {
+	data, err := util.ReadFile(fs, ".lfsconfig")
+	if err != nil {
+		return ""
+	}
+	cfg := formatconfig.New()
+	if err := formatconfig.NewDecoder(bytes.NewReader(data)).Decode(cfg); err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(cfg.Section("lfs").Option("url"), "/")
+}
+
+// lfsEndpoint returns the LFS server endpoint derived from the remote URL.
+func lfsEndpoint(remote string) (string, error) {
+	endpoint, err := transport.NewEndpoint(remote)
+	if err != nil {
+		return "", err
+	}
+	scheme := endpoint.Protocol
+	switch scheme {
+	case "http", "https":
+	case "ssh", "git":
+		scheme = "https"
+	default:
+		return "", fmt.Errorf("git lfs is not supported for the %s protocol", endpoint.Protocol)
+	}
+
+	host := endpoint.Host
+	if endpoint.Port != 0 && scheme == endpoint.Protocol {
+		host = fmt.Sprintf("%s:%d", endpoint.Host, endpoint.Port)
+	}
+	p := "/" + strings.Trim(endpoint.Path, "/")
+	if !strings.HasSuffix(p, ".git") {
+		p += ".git"
+	}
+	u := url.URL{Scheme: scheme, Host: host, Path: p + "/info/lfs"}
+	return u.String(), nil
+}
+
+// batch requests the download actions of the objects.
+func (c *lfsClient) batch(ctx context.Context, pointers []lfsPointer) (map[string]*lfsAction, error) {
+	body, err := json.Marshal(lfsBatchRequest{
+		Operation: "download",
+		Transfers: []string{"basic"},
+		Objects:   pointers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodPost, c.endpoint+"/objects/batch", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", LFSMediaType)
+	req.Header.Set("Content-Type", LFSMediaType)
+	// The endpoint set by the template itself may be any host, it only gets the credentials of the clone remote
+	// if it is served by the same host
+	if c.sameOrigin(req.URL) {
+		c.authorize(req)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	var batch lfsBatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		return nil, fmt.Errorf("invalid lfs batch response: %w", err)
+	}
+
+	actions := make(map[string]*lfsAction)
+	for _, object := range batch.Objects {
+		if object.Error != nil {
+			return nil, fmt.Errorf("lfs object %s: %s (%d)", object.OID, object.Error.Message, object.Error.Code)
+		}
+		if object.Actions.Download == nil {
+			return nil, fmt.Errorf("lfs object %s: no download action", object.OID)
+		}
+		actions[object.OID] = object.Actions.Download
+	}
+	return actions, nil
+}
+
+// download downloads the object and verifies its size and hash.
+func (c *lfsClient) download(ctx context.Context, pointer lfsPointer, action *lfsAction) ([]byte, error) {
+	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodGet, action.Href, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The credentials of the repository are never sent to other hosts, e.g. a storage service or a CDN
+	if len(action.Header) == 0 && c.sameOrigin(req.URL) {
+		c.authorize(req)
+	}
+	for key, value := range action.Header {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, pointer.Size+1))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != pointer.Size || hex.EncodeToString(sum[:]) != pointer.OID {
+		return nil, fmt.Errorf("lfs object %s: content does not match the pointer", pointer.OID)
+	}
+	return data, nil
+}
+
+// sameOrigin reports whether the URL has the scheme and the host of the LFS endpoint of the clone remote.
+func (c *lfsClient) sameOrigin(u *url.URL) bool {
+	if len(c.origin) == 0 {
+		return false
+	}
+	origin, err := url.Parse(c.origin)
+	return err == nil && strings.EqualFold(u.Scheme, origin.Scheme) && strings.EqualFold(u.Host, origin.Host)
+}
+
+func (c *lfsClient) authorize(req *nethttp.Request) {
+	authorize(req, c.auth)
+}
+
+// authorize adds the authentication of the repository to the request, only HTTP authentications are supported.
+func authorize(req *nethttp.Request, auth transport.AuthMethod) {
+	switch auth := auth.(type) {
+	case *http.BasicAuth:
+		req.SetBasicAuth(auth.Username, auth.Password)
+	case *http.TokenAuth:
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	}
+}
+
+// checkStatus returns the error of a response with an unexpected status code.
+func checkStatus(resp *nethttp.Response) error {
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == nethttp.StatusUnauthorized:
+		return transport.ErrAuthenticationRequired
+	case resp.StatusCode == nethttp.StatusForbidden:
+		return transport.ErrAuthorizationFailed
+	}
+	return &statusError{URL: resp.Request.URL.Redacted(), StatusCode: resp.StatusCode}
+}
+
+// materializeLFS handles the LFS pointer files in fs by the LFS mode: the pointers are replaced with the
+// downloaded objects, kept, or removed.
+func (d *DegitService) materializeLFS(ctx context.Context, fs billy.Filesystem) error {
+	if d.lfs == LFSPointer {
+		return nil
+	}
+
+	pointers := make(map[string]lfsPointer)
+	err := d.walk(ctx, fs, "", func(path string, fs billy.Filesystem) error {
+		fi, err := fs.Lstat(path)
+		if err != nil || !fi.Mode().IsRegular() || fi.Size() > LFSPointerMaxSize {
+			return err
+		}
+		data, err := util.ReadFile(fs, path)
+		if err != nil {
+			return err
+		}
+		if pointer, ok := parseLFSPointer(data); ok {
+			pointers[path] = pointer
+		}
+		return nil
+	})
+	if err != nil || len(pointers) == 0 {
+		return err
+	}
+
+	if d.lfs == LFSSkip {
+		for path := range pointers {
+			d.log("skip lfs object: %s", path)
+			if err := fs.Remove(path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if d.local != nil {
+		for path, pointer := range pointers {
+			d.log("copy lfs object: %s", path)
+			data, err := d.local.lfsObject(pointer)
+			if err != nil {
+				return err
+			}
+			if err := replaceFile(fs, path, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	origin, err := lfsEndpoint(d.remote)
+	endpoint := lfsConfigURL(fs)
+	if len(endpoint) == 0 {
+		if err != nil {
+			return err
+		}
+		endpoint = origin
+	}
+	// The LFS server is accessed over HTTPS, the SSH keys cannot authenticate to it
+	sshRemote := false
+	if e, err := transport.NewEndpoint(d.remote); err == nil {
+		sshRemote = e.Protocol == "ssh"
+	}
+	d.log("fetch %d lfs objects from %s", len(pointers), endpoint)
+	client := &lfsClient{endpoint: endpoint, origin: origin, auth: d.authMethod, client: nethttp.DefaultClient}
+
+	// Deduplicate the objects shared by multiple files
+	var unique []lfsPointer
+	seen := make(map[string]bool)
+	for _, pointer := range pointers {
+		if !seen[pointer.OID] {
+			seen[pointer.OID] = true
+			unique = append(unique, pointer)
+		}
+	}
+
+	objects := make(map[string][]byte)
+	for start := 0; start < len(unique); start += LFSBatchSize {
+		chunk := unique[start:min(start+LFSBatchSize, len(unique))]
+		err := d.retry(ctx, func() error {
+			actions, err := client.batch(ctx, chunk)
+			if err != nil {
+				return err
+			}
+			for _, pointer := range chunk {
+				if _, ok := objects[pointer.OID]; ok {
+					continue
+				}
+				action, ok := actions[pointer.OID]
+				if !ok {
+					return fmt.Errorf("lfs object %s: missing in the batch response", pointer.OID)
+				}
+				data, err := client.download(ctx, pointer, action)
+				if err != nil {
+					return err
+				}
+				objects[pointer.OID] = data
+			}
+			return nil
+		})
+		if sshRemote && (errors.Is(err, transport.ErrAuthenticationRequired) || errors.Is(err, transport.ErrAuthorizationFailed)) {
+			return fmt.Errorf("lfs server %s: %w: the LFS objects of an SSH remote require HTTP credentials, "+
+				"clone the HTTPS URL with a username and password, or keep the pointer files", endpoint, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for path, pointer := range pointers {
+		d.log("fetch lfs object: %s", path)
+		if err := replaceFile(fs, path, objects[pointer.OID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceFile replaces the content of the file and keeps its permissions.
+func replaceFile(fs billy.Filesystem, path string, data []byte) error {
+	fi, err := fs.Lstat(path)
+	if err != nil {
+		return err
+	}
+	return util.WriteFile(fs, path, data, fi.Mode().Perm())
+}
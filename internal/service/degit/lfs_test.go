@@ -0,0 +1,279 @@
+package degit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+// newLFSServer starts a stand-in of a Git LFS server, which serves the objects with the basic transfer
+// and requires the basic authentication "user:secret".
+func newLFSServer(t *testing.T, objects map[string]string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("POST /repo.git/info/lfs/objects/batch", func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req lfsBatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Operation != "download" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var objs []map[string]any
+		for _, p := range req.Objects {
+			obj := map[string]any{"oid": p.OID, "size": p.Size}
+			if _, ok := objects[p.OID]; ok {
+				obj["actions"] = map[string]any{
+					"download": map[string]any{
+						"href":   fmt.Sprintf("%s/objects/%s", server.URL, p.OID),
+						"header": map[string]string{"X-Token": "token-" + p.OID},
+					},
+				}
+			} else {
+				obj["error"] = map[string]any{"code": 404, "message": "object not found"}
+			}
+			objs = append(objs, obj)
+		}
+		w.Header().Set("Content-Type", LFSMediaType)
+		json.NewEncoder(w).Encode(map[string]any{"transfer": "basic", "objects": objs})
+	})
+	mux.HandleFunc("GET /objects/{oid}", func(w http.ResponseWriter, r *http.Request) {
+		oid := r.PathValue("oid")
+		if r.Header.Get("X-Token") != "token-"+oid {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(objects[oid]))
+	})
+
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func lfsPointerOf(content string) (string, string) {
+	sum := sha256.Sum256([]byte(content))
+	oid := hex.EncodeToString(sum[:])
+	return oid, fmt.Sprintf("%s\noid sha256:%s\nsize %d\n", lfsPointerVersion, oid, len(content))
+}
+
+func TestParseLFSPointer(t *testing.T) {
+	oid, pointer := lfsPointerOf("hello")
+
+	got, ok := parseLFSPointer([]byte(pointer))
+	if !ok || got.OID != oid || got.Size != 5 {
+		t.Errorf("parseLFSPointer(%q) = %v, %v; want {%s 5}, true", pointer, got, ok, oid)
+	}
+
+	for _, data := range []string{
+		"hello",
+		lfsPointerVersion + "\noid sha256:abc\nsize 5\n",
+		lfsPointerVersion + "\nsize 5\n",
+		"oid sha256:" + oid + "\nsize 5\n",
+		strings.Repeat("x", LFSPointerMaxSize+1),
+	} {
+		if _, ok := parseLFSPointer([]byte(data)); ok {
+			t.Errorf("parseLFSPointer(%q) should not be a pointer", data)
+		}
+	}
+}
+
+func TestLFSEndpoint(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/user/repo":        "https://github.com/user/repo.git/info/lfs",
+		"https://github.com/user/repo.git":    "https://github.com/user/repo.git/info/lfs",
+		"http://localhost:8080/repo.git":      "http://localhost:8080/repo.git/info/lfs",
+		"git@gitlab.com:user/repo.git":        "https://gitlab.com/user/repo.git/info/lfs",
+		"ssh://git@example.com:2222/repo.git": "https://example.com/repo.git/info/lfs",
+	}
+	for remote, want := range tests {
+		got, err := lfsEndpoint(remote)
+		if err != nil || got != want {
+			t.Errorf("lfsEndpoint(%q) = %q, %v; want %q", remote, got, err, want)
+		}
+	}
+}
+
+func TestLFSConfigURL(t *testing.T) {
+	tests := map[string]string{
+		"": "",
+		"[lfs]\n\turl = https://lfs.example.com/repo/\n": "https://lfs.example.com/repo",
+		"[core]\n\tbare = false\n":                       "",
+	}
+	for config, want := range tests {
+		fs := memfs.New()
+		if len(config) != 0 {
+			util.WriteFile(fs, ".lfsconfig", []byte(config), 0644)
+		}
+		if got := lfsConfigURL(fs); got != want {
+			t.Errorf("lfsConfigURL(%q) = %q; want %q", config, got, want)
+		}
+	}
+}
+
+func TestLFSClient_download(t *testing.T) {
+	content := "object content"
+	oid, _ := lfsPointerOf(content)
+	pointer := lfsPointer{OID: oid, Size: int64(len(content))}
+
+	// The servers record the Authorization headers, they differ in the port only
+	var authorizations []string
+	record := func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		w.Write([]byte(content))
+	}
+	server := httptest.NewServer(http.HandlerFunc(record))
+	t.Cleanup(server.Close)
+	foreign := httptest.NewServer(http.HandlerFunc(record))
+	t.Cleanup(foreign.Close)
+
+	client := &lfsClient{
+		endpoint: server.URL + "/repo.git/info/lfs",
+		origin:   server.URL + "/repo.git/info/lfs",
+		auth:     NewDegitServiceWithBasicAuth(server.URL+"/repo.git", "user", "secret").authMethod,
+		client:   http.DefaultClient,
+	}
+	for _, href := range []string{server.URL + "/objects/" + oid, foreign.URL + "/objects/" + oid} {
+		data, err := client.download(context.Background(), pointer, &lfsAction{Href: href})
+		if err != nil || string(data) != content {
+			t.Fatalf("download(%q) = %q, %v; want %q", href, data, err, content)
+		}
+	}
+
+	if len(authorizations) != 2 || authorizations[0] == "" || authorizations[1] != "" {
+		t.Errorf("Authorization headers = %q; want the credentials for the endpoint host only", authorizations)
+	}
+}
+
+func TestDegitService_materializeLFS(t *testing.T) {
+	image := "\x89PNG image content"
+	font := "font content"
+	imageOID, imagePointer := lfsPointerOf(image)
+	fontOID, fontPointer := lfsPointerOf(font)
+	server := newLFSServer(t, map[string]string{imageOID: image, fontOID: font})
+
+	setup := func() billy.Filesystem {
+		fs := memfs.New()
+		util.WriteFile(fs, "README.md", []byte("# readme\n"), 0644)
+		util.WriteFile(fs, "assets/logo.png", []byte(imagePointer), 0644)
+		util.WriteFile(fs, "assets/copy.png", []byte(imagePointer), 0644)
+		util.WriteFile(fs, "fonts/font.woff", []byte(fontPointer), 0644)
+		return fs
+	}
+
+	type testcase struct {
+		mode LFSMode
+		want map[string]string
+	}
+
+	tests := []testcase{
+		{LFSFetch, map[string]string{
+			"README.md": "# readme\n", "assets/logo.png": image, "assets/copy.png": image, "fonts/font.woff": font,
+		}},
+		{LFSPointer, map[string]string{
+			"README.md": "# readme\n", "assets/logo.png": imagePointer, "fonts/font.woff": fontPointer,
+		}},
+		{LFSSkip, map[string]string{
+			"README.md": "# readme\n", "assets/logo.png": "", "fonts/font.woff": "",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("mode %d", test.mode), func(t *testing.T) {
+			fs := setup()
+			d := NewDegitServiceWithBasicAuth(server.URL+"/repo.git", "user", "secret")
+			d.SetLFS(test.mode)
+
+			if err := d.materializeLFS(context.Background(), fs); err != nil {
+				t.Fatalf("materializeLFS() error: %v", err)
+			}
+			for path, want := range test.want {
+				data, err := util.ReadFile(fs, path)
+				if want == "" {
+					if err == nil {
+						t.Errorf("%s should be removed", path)
+					}
+					continue
+				}
+				if string(data) != want {
+					t.Errorf("%s = %q; want %q", path, data, want)
+				}
+			}
+		})
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		d := NewDegitServiceWithBasicAuth(server.URL+"/repo.git", "user", "wrong")
+		if err := d.materializeLFS(context.Background(), setup()); err == nil {
+			t.Errorf("materializeLFS() should fail with wrong credentials")
+		}
+	})
+
+	t.Run("ssh remote", func(t *testing.T) {
+		fs := setup()
+		util.WriteFile(fs, ".lfsconfig", []byte("[lfs]\n\turl = "+server.URL+"/repo.git/info/lfs\n"), 0644)
+
+		d := NewDegitService("ssh://git@example.com/repo.git")
+		err := d.materializeLFS(context.Background(), fs)
+		if err == nil || !strings.Contains(err.Error(), "require HTTP credentials") {
+			t.Errorf("materializeLFS() error = %v; want the error of the HTTP credentials", err)
+		}
+	})
+
+	t.Run("lfsconfig on the same host", func(t *testing.T) {
+		fs := setup()
+		util.WriteFile(fs, ".lfsconfig", []byte("[lfs]\n\turl = "+server.URL+"/repo.git/info/lfs\n"), 0644)
+
+		d := NewDegitServiceWithBasicAuth(server.URL+"/other.git", "user", "secret")
+		if err := d.materializeLFS(context.Background(), fs); err != nil {
+			t.Errorf("materializeLFS() error: %v", err)
+		}
+	})
+
+	t.Run("lfsconfig on another host", func(t *testing.T) {
+		var authorizations []string
+		foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorizations = append(authorizations, r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		t.Cleanup(foreign.Close)
+
+		fs := setup()
+		util.WriteFile(fs, ".lfsconfig", []byte("[lfs]\n\turl = "+foreign.URL+"/repo.git/info/lfs\n"), 0644)
+
+		d := NewDegitServiceWithBasicAuth(server.URL+"/repo.git", "user", "secret")
+		if err := d.materializeLFS(context.Background(), fs); err == nil {
+			t.Errorf("materializeLFS() should fail without credentials")
+		}
+		if len(authorizations) == 0 || strings.Join(authorizations, "") != "" {
+			t.Errorf("Authorization headers = %q; want no credentials for another host", authorizations)
+		}
+	})
+
+	t.Run("missing object", func(t *testing.T) {
+		fs := setup()
+		_, pointer := lfsPointerOf("missing")
+		util.WriteFile(fs, "missing.bin", []byte(pointer), 0644)
+
+		d := NewDegitServiceWithBasicAuth(server.URL+"/repo.git", "user", "secret")
+		if err := d.materializeLFS(context.Background(), fs); err == nil {
+			t.Errorf("materializeLFS() should fail with a missing object")
+		}
+	})
+}
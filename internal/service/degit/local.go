@@ -0,0 +1,353 @@
+package degit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+const (
+	BundleExtension = ".bundle"
+)
+
+// LocalPath returns the path of the remote on the local filesystem, false is returned if the remote is neither
+// a "file://" URL nor an explicit path: an absolute path, or a path starting with "./" or "../". A relative path
+// like "user/repo" is not local, even if it exists, so that it cannot shadow the GitHub shortcut.
+func LocalPath(remote string) (string, bool) {
+	if strings.HasPrefix(remote, "file://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return "", false
+		}
+		return filepath.FromSlash(u.Path), true
+	}
+
+	if filepath.IsAbs(remote) {
+		return remote, true
+	}
+	for _, dir := range []string{".", ".."} {
+		if remote == dir || strings.HasPrefix(remote, dir+"/") || strings.HasPrefix(remote, dir+string(filepath.Separator)) {
+			return remote, true
+		}
+	}
+	return "", false
+}
+
+// localRepository is a repository read directly from the local filesystem: a working tree, a bare repository,
+// or a bundle file.
+type localRepository struct {
+	repo *git.Repository
+	// worktree is the root of the working tree, empty for bare repositories and bundles
+	worktree string
+	// gitDir is the git directory containing the LFS objects, empty for bundles
+	gitDir string
+}
+
+// SetIncludeUncommitted sets whether the current state of a local working tree is copied, including the uncommitted
+// changes, instead of a committed reference.
+func (d *DegitService) SetIncludeUncommitted(includeUncommitted bool) {
+	d.includeUncommitted = includeUncommitted
+}
+
+// openLocal opens the local repository of the remote once, `nil` is returned if the remote is not local.
+func (d *DegitService) openLocal() (*localRepository, error) {
+	if d.local != nil {
+		return d.local, nil
+	}
+	path, ok := LocalPath(d.remote)
+	if !ok {
+		return nil, nil
+	}
+
+	var local *localRepository
+	var err error
+	if fi, statErr := os.Stat(path); statErr == nil && !fi.IsDir() && strings.HasSuffix(path, BundleExtension) {
+		local, err = openBundle(path)
+	} else {
+		local, err = openRepository(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	d.log("open local repository: %s", path)
+	d.local = local
+	return local, nil
+}
+
+func openRepository(path string) (*localRepository, error) {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return nil, err
+	}
+
+	local := &localRepository{repo: repo, gitDir: path}
+	if fi, err := os.Stat(filepath.Join(path, git.GitDirName)); err == nil && fi.IsDir() {
+		local.worktree = path
+		local.gitDir = filepath.Join(path, git.GitDirName)
+	}
+	return local, nil
+}
+
+// openBundle reads a bundle file created by "git bundle create" into a repository in memory.
+// Bundles with prerequisites are not supported, since their history is incomplete.
+func openBundle(path string) (*localRepository, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	header = strings.TrimSpace(header)
+	if header != "# v2 git bundle" && header != "# v3 git bundle" {
+		return nil, fmt.Errorf("invalid bundle: unsupported header '%s'", header)
+	}
+
+	var refs []*plumbing.Reference
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle: %w", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if len(line) == 0 {
+			break
+		}
+
+		switch line[0] {
+		case '@':
+			// Capabilities of the v3 format
+			continue
+		case '-':
+			return nil, fmt.Errorf("bundle with prerequisites is not supported")
+		}
+		hash, name, ok := strings.Cut(line, " ")
+		if !ok || !plumbing.IsHash(hash) {
+			return nil, fmt.Errorf("invalid bundle: invalid reference '%s'", line)
+		}
+		refs = append(refs, plumbing.NewReferenceFromStrings(name, hash))
+	}
+
+	storage := memory.NewStorage()
+	if err := packfile.UpdateObjectStorage(storage, reader); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	for _, ref := range refs {
+		if err := storage.SetReference(ref); err != nil {
+			return nil, err
+		}
+	}
+	// A bundle of some branches, e.g. "git bundle create t.bundle main", has no HEAD, the first reference is used
+	if _, err := storage.Reference(plumbing.HEAD); err != nil && len(refs) != 0 {
+		if err := storage.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, refs[0].Name())); err != nil {
+			return nil, err
+		}
+	}
+
+	repo, err := git.Open(storage, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &localRepository{repo: repo}, nil
+}
+
+// references returns the references of the repository like [DegitService.ListContext].
+func (l *localRepository) references() ([]*plumbing.Reference, error) {
+	iter, err := l.repo.References()
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []*plumbing.Reference
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		refs = append(refs, ref)
+		if !ref.Name().IsTag() {
+			return nil
+		}
+		if tag, err := l.repo.TagObject(ref.Hash()); err == nil {
+			refs = append(refs, plumbing.NewHashReference(ref.Name()+PeeledSuffix, tag.Target))
+		}
+		return nil
+	})
+	return refs, err
+}
+
+// checkout writes the files of the commit into fs. The submodules cannot be cloned from a local repository, so
+// they are all skipped and their paths are returned.
+func (l *localRepository) checkout(fs billy.Filesystem, commit plumbing.Hash) ([]string, error) {
+	c, err := l.repo.CommitObject(commit)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var skipped []string
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch entry.Mode {
+		case filemode.Dir:
+			continue
+		case filemode.Submodule:
+			skipped = append(skipped, name)
+			continue
+		}
+
+		blob, err := l.repo.BlobObject(entry.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBlob(fs, name, entry.Mode, blob); err != nil {
+			return nil, err
+		}
+	}
+	return skipped, nil
+}
+
+// copyWorktree copies the current state of the working tree into fs, except the git directories and the ignored
+// files. The checked out submodules are copied if they are selected, the paths of the skipped ones are returned.
+func (l *localRepository) copyWorktree(fs billy.Filesystem, selected func(path string) bool) ([]string, error) {
+	if len(l.worktree) == 0 {
+		return nil, fmt.Errorf("no working tree to copy the uncommitted changes from")
+	}
+
+	src := osfs.New(l.worktree)
+	patterns, err := gitignore.ReadPatterns(src, nil)
+	if err != nil {
+		return nil, err
+	}
+	matcher := gitignore.NewMatcher(patterns)
+
+	var skipped []string
+	var copyDir func(dir []string) error
+	copyDir = func(dir []string) error {
+		entries, err := src.ReadDir(filepath.Join(dir...))
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			// The git directory of a submodule is a ".git" file pointing into the git directory of its parent
+			if entry.Name() == git.GitDirName {
+				continue
+			}
+			p := append(append([]string{}, dir...), entry.Name())
+			if matcher.Match(p, entry.IsDir()) {
+				continue
+			}
+			if entry.IsDir() {
+				if _, err := src.Lstat(filepath.Join(append(p, git.GitDirName)...)); err == nil && !selected(path.Join(p...)) {
+					skipped = append(skipped, path.Join(p...))
+					continue
+				}
+				if err := copyDir(p); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := copyFile(src, fs, filepath.Join(p...), entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return skipped, copyDir(nil)
+}
+
+// lfsObject reads the LFS object from the local LFS storage of the repository.
+func (l *localRepository) lfsObject(pointer lfsPointer) ([]byte, error) {
+	if len(l.gitDir) == 0 {
+		return nil, fmt.Errorf("lfs object %s: no local lfs storage", pointer.OID)
+	}
+	data, err := os.ReadFile(filepath.Join(l.gitDir, "lfs", "objects", pointer.OID[0:2], pointer.OID[2:4], pointer.OID))
+	if err != nil {
+		return nil, fmt.Errorf("lfs object %s: %w", pointer.OID, err)
+	}
+	return data, nil
+}
+
+func writeBlob(fs billy.Filesystem, name string, mode filemode.FileMode, blob *object.Blob) error {
+	r, err := blob.Reader()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if mode == filemode.Symlink {
+		target, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return fs.Symlink(string(target), name)
+	}
+
+	perm, err := mode.ToOSFileMode()
+	if err != nil {
+		return err
+	}
+	f, err := fs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, r)
+	return err
+}
+
+func copyFile(src billy.Filesystem, dst billy.Filesystem, name string, fi os.FileInfo) error {
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := src.Readlink(name)
+		if err != nil {
+			return err
+		}
+		return dst.Symlink(target, name)
+	}
+	if !fi.Mode().IsRegular() {
+		return nil
+	}
+
+	r, err := src.Open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := dst.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
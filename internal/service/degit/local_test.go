@@ -0,0 +1,277 @@
+package degit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// newBareRepository copies the objects and the "main" branch of the repository into a new bare repository.
+func newBareRepository(t *testing.T, src *git.Repository, commit plumbing.Hash) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	bare, err := git.PlainInit(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iter, err := src.Storer.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = iter.ForEach(func(obj plumbing.EncodedObject) error {
+		_, err := bare.Storer.SetEncodedObject(obj)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bare.Storer.SetReference(plumbing.NewHashReference("refs/heads/main", commit)); err != nil {
+		t.Fatal(err)
+	}
+	if err := bare.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/main")); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// newBundle writes the objects and the "main" branch of the repository into a v2 bundle file.
+func newBundle(t *testing.T, src *git.Repository, commit plumbing.Hash) string {
+	t.Helper()
+
+	var hashes []plumbing.Hash
+	iter, err := src.Storer.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iter.ForEach(func(obj plumbing.EncodedObject) error {
+		hashes = append(hashes, obj.Hash())
+		return nil
+	})
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# v2 git bundle\n%s refs/heads/main\n\n", commit)
+	if _, err := packfile.NewEncoder(&buf, src.Storer, false).Encode(hashes, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "template"+BundleExtension)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// addSubmodule commits a submodule entry at the path of the "main" branch, without its repository.
+func addSubmodule(t *testing.T, repo *git.Repository, parent plumbing.Hash, name string) plumbing.Hash {
+	t.Helper()
+
+	store := func(obj interface {
+		Encode(plumbing.EncodedObject) error
+	}) plumbing.Hash {
+		encoded := repo.Storer.NewEncodedObject()
+		if err := obj.Encode(encoded); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := repo.Storer.SetEncodedObject(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+
+	commit, err := repo.CommitObject(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := append(slices.Clone(tree.Entries), object.TreeEntry{Name: name, Mode: filemode.Submodule, Hash: parent})
+	slices.SortFunc(entries, func(a, b object.TreeEntry) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	next := &object.Commit{
+		Author:       commit.Author,
+		Committer:    commit.Committer,
+		Message:      "add submodule",
+		TreeHash:     store(&object.Tree{Entries: entries}),
+		ParentHashes: []plumbing.Hash{parent},
+	}
+	hash := store(next)
+	if err := repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/main", hash)); err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// readFiles returns the content of the files in fs by their slash-separated paths.
+func readFiles(t *testing.T, fs billy.Filesystem) map[string]string {
+	t.Helper()
+
+	files := make(map[string]string)
+	err := util.Walk(fs, "", func(path string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		data, err := util.ReadFile(fs, path)
+		files[filepath.ToSlash(path)] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestLocalPath(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll(filepath.Join("user", "repo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	abs, err := filepath.Abs("template")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testcase struct {
+		remote string
+		want   string
+		ok     bool
+	}
+
+	tests := []testcase{
+		{".", ".", true},
+		{"..", "..", true},
+		{"./template", "./template", true},
+		{"../template.bundle", "../template.bundle", true},
+		{abs, abs, true},
+		{"file:///srv/git/template.git", filepath.FromSlash("/srv/git/template.git"), true},
+		// A relative path without "./" is the GitHub shortcut or a remote URL, even if the directory exists
+		{"user/repo", "", false},
+		{"user", "", false},
+		{".template", "", false},
+		{"https://github.com/user/repo.git", "", false},
+		{"git@github.com:user/repo.git", "", false},
+	}
+
+	for _, test := range tests {
+		got, ok := LocalPath(test.remote)
+		if got != test.want || ok != test.ok {
+			t.Errorf("LocalPath(%q) = %q, %v; want %q, %v", test.remote, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestDegitService_local(t *testing.T) {
+	files := map[string]string{
+		"README.md":   "# Template\n",
+		"src/main.go": "package main\n",
+	}
+	worktree, commit := newTestRepository(t, files)
+	repo, err := git.PlainOpen(worktree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sources := map[string]string{
+		"worktree": worktree,
+		"file url": "file://" + filepath.ToSlash(worktree),
+		"bare":     newBareRepository(t, repo, commit),
+		"bundle":   newBundle(t, repo, commit),
+	}
+	for name, remote := range sources {
+		t.Run(name, func(t *testing.T) {
+			d := NewDegitService(remote)
+			dest := NewMemoryDestination()
+			if err := d.CloneContext(context.Background(), "main", dest, false); err != nil {
+				t.Fatal(err)
+			}
+			if got := readFiles(t, dest.Filesystem()); !reflect.DeepEqual(got, files) {
+				t.Errorf("files = %v; want %v", got, files)
+			}
+		})
+	}
+}
+
+func TestDegitService_localSubmodules(t *testing.T) {
+	worktree, commit := newTestRepository(t, map[string]string{"README.md": "# Template\n"})
+	repo, err := git.PlainOpen(worktree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addSubmodule(t, repo, commit, "lib")
+
+	// The submodules are skipped whatever the mode, they cannot be cloned from a local repository
+	for _, mode := range []SubmoduleMode{SubmodulesShallow, SubmodulesFull, SubmodulesNone} {
+		d := NewDegitService(worktree)
+		d.SetSubmodules(mode)
+		if err := d.CloneContext(context.Background(), "main", NewMemoryDestination(), false); err != nil {
+			t.Fatalf("CloneContext() with the %v mode error: %v", mode, err)
+		}
+		if got := d.SkippedSubmodules(); !slices.Equal(got, []string{"lib"}) {
+			t.Errorf("SkippedSubmodules() with the %v mode = %q; want [lib]", mode, got)
+		}
+	}
+}
+
+func TestDegitService_includeUncommitted(t *testing.T) {
+	worktree, _ := newTestRepository(t, map[string]string{
+		"README.md":  "# Template\n",
+		".gitignore": "*.log\n",
+	})
+	for name, content := range map[string]string{
+		"README.md":       "# Changed\n",
+		"debug.log":       "ignored\n",
+		"new.txt":         "uncommitted\n",
+		"lib/.git":        "gitdir: ../.git/modules/lib\n",
+		"lib/lib.go":      "package lib\n",
+		"vendor/x/.git":   "gitdir: ../../.git/modules/x\n",
+		"vendor/x/x.go":   "package x\n",
+		"vendor/keep.txt": "keep\n",
+	} {
+		path := filepath.Join(worktree, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDegitService(worktree)
+	d.SetIncludeUncommitted(true)
+	d.SetSubmodules(SubmodulesShallow, "lib")
+	dest := NewMemoryDestination()
+	if err := d.CloneContext(context.Background(), "", dest, false); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		".gitignore":      "*.log\n",
+		"README.md":       "# Changed\n",
+		"new.txt":         "uncommitted\n",
+		"lib/lib.go":      "package lib\n",
+		"vendor/keep.txt": "keep\n",
+	}
+	if got := readFiles(t, dest.Filesystem()); !reflect.DeepEqual(got, want) {
+		t.Errorf("files = %v; want %v", got, want)
+	}
+	if got := d.SkippedSubmodules(); !slices.Equal(got, []string{"vendor/x"}) {
+		t.Errorf("SkippedSubmodules() = %q; want [vendor/x]", got)
+	}
+}
@@ -0,0 +1,201 @@
+package degit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/sotvokun/emit/internal/service/version"
+)
+
+const (
+	DefaultLockFile = ".emit/lock.json"
+	LockVersion     = 1
+
+	lockHashPrefix = "sha256:"
+)
+
+// Lock records where the files of a destination came from, it is written as JSON.
+type Lock struct {
+	// Version is the version of the lock format, see [LockVersion]
+	Version int `json:"version"`
+	// Remote is the normalized remote without credentials, local paths are absolute
+	Remote string `json:"remote"`
+	// Ref is the ref as requested, e.g. "main" or "^1.2"
+	Ref string `json:"ref,omitempty"`
+	// Reference is the full name of the resolved reference, empty for archive sources
+	Reference string `json:"reference,omitempty"`
+	// Commit is the hash of the resolved commit, empty for archive sources
+	Commit  string   `json:"commit,omitempty"`
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	// Mode, Submodules and LFS are the names of the modes of the clone, e.g. "git", "shallow" and "fetch",
+	// they are empty in the lock files written before they were recorded, which used the default modes
+	Mode       string `json:"mode,omitempty"`
+	Submodules string `json:"submodules,omitempty"`
+	// SubmodulePaths are the paths of the selected submodules, all submodules are selected if it is empty
+	SubmodulePaths []string `json:"submodulePaths,omitempty"`
+	LFS            string   `json:"lfs,omitempty"`
+	// Emit is the version of emit which wrote the files
+	Emit string `json:"emit"`
+	// Files maps the slash-separated path of each written file to the hash of its content, e.g. "sha256:..."
+	Files map[string]string `json:"files"`
+}
+
+// SetLockFile sets the path of the lock file written into the destination after the files,
+// no lock file is written if the path is empty, see [DefaultLockFile].
+func (d *DegitService) SetLockFile(path string) {
+	d.lockFile = path
+}
+
+// ReadLock reads the lock file at the path of the OS filesystem.
+func ReadLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := ParseLock(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return lock, nil
+}
+
+// ParseLock parses the content of a lock file.
+func ParseLock(data []byte) (*Lock, error) {
+	lock := &Lock{}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("invalid lock file: %w", err)
+	}
+	if lock.Version != LockVersion {
+		return nil, fmt.Errorf("unsupported lock file version %d", lock.Version)
+	}
+	if lock.Files == nil {
+		lock.Files = make(map[string]string)
+	}
+	// The recorded paths are read and compared in the destination, they must not escape it
+	for path := range lock.Files {
+		if _, err := archivePath(path); err != nil {
+			return nil, fmt.Errorf("invalid lock file: %w", err)
+		}
+	}
+	return lock, nil
+}
+
+// newLock creates the lock of the resolution without files.
+func (d *DegitService) newLock(resolution *Resolution) *Lock {
+	lock := &Lock{
+		Version: LockVersion,
+		Remote:  NormalizeRemote(d.remote),
+		Ref:     resolution.Requested,
+		Include: d.filters.includePatterns,
+		Exclude: d.filters.excludePatterns,
+
+		Mode:           d.mode.String(),
+		Submodules:     d.submodules.mode.String(),
+		SubmodulePaths: d.submodules.paths,
+		LFS:            d.lfs.String(),
+
+		Emit:  version.NewVersionService().Version(),
+		Files: make(map[string]string),
+	}
+	if resolution.Reference != nil {
+		lock.Reference = resolution.Reference.Name().String()
+		lock.Commit = resolution.Commit.String()
+	}
+	return lock
+}
+
+// addFile records the hash of the file in fs, see [HashFile].
+func (l *Lock) addFile(path string, fs billy.Filesystem) error {
+	hash, err := HashFile(fs, path)
+	if err != nil {
+		return err
+	}
+	l.Files[filepath.ToSlash(path)] = hash
+	return nil
+}
+
+// HashFile returns the hash of the file in fs as it is recorded in a lock file, e.g. "sha256:...".
+// The target is hashed for symlinks.
+func HashFile(fs billy.Filesystem, path string) (string, error) {
+	fi, err := fs.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := fs.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		io.WriteString(h, target)
+	} else {
+		f, err := fs.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+	}
+	return lockHashPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ValidateLockFile checks that the slash-separated path of a lock file is relative and within the destination,
+// e.g. ".emit/lock.json" but not "/tmp/lock.json" or "../lock.json".
+func ValidateLockFile(path string) error {
+	if !filepath.IsLocal(filepath.FromSlash(path)) || strings.HasPrefix(path, "/") {
+		return fmt.Errorf("lock file %q must be a relative path within the destination", path)
+	}
+	return nil
+}
+
+// writeLock writes the lock file with the walker.
+func (d *DegitService) writeLock(walker *Walker, lock *Lock) error {
+	data, err := lock.Marshal()
+	if err != nil {
+		return err
+	}
+	return walker.WriteFile(filepath.FromSlash(d.lockFile), data)
+}
+
+// Marshal encodes the lock as indented JSON, the files are sorted by path.
+func (l *Lock) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// NormalizeRemote returns the remote without credentials. The user information of HTTP URLs is removed,
+// since it may contain a token, and local paths are made absolute.
+func NormalizeRemote(remote string) string {
+	if path, ok := LocalPath(remote); ok {
+		if abs, err := filepath.Abs(path); err == nil {
+			return abs
+		}
+		return path
+	}
+
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil {
+		return remote
+	}
+	if u.Scheme == "http" || u.Scheme == "https" {
+		u.User = nil
+	} else {
+		u.User = url.User(u.User.Username())
+	}
+	return u.String()
+}
@@ -0,0 +1,77 @@
+package degit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+const (
+	RetryInitialDelay = 1 * time.Second
+	RetryMaxDelay     = 30 * time.Second
+)
+
+// retry calls fn until it succeeds, returns a non-transient error, or the retries are used up.
+// The delay between attempts starts at [RetryInitialDelay] and doubles up to [RetryMaxDelay].
+func (d *DegitService) retry(ctx context.Context, fn func() error) error {
+	delay := d.retryDelay
+	if delay == 0 {
+		delay = RetryInitialDelay
+	}
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= d.retries || !isTransient(err) || ctx.Err() != nil {
+			return err
+		}
+
+		d.log("attempt %d failed: %v, retrying in %s", attempt+1, err, delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, RetryMaxDelay)
+	}
+}
+
+// isTransient reports whether the error is worth retrying, e.g. network failures and server errors.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var unexpected *plumbing.UnexpectedError
+	if errors.As(err, &unexpected) {
+		err = unexpected.Err
+	}
+
+	var httpErr *http.Err
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode() >= 500 || httpErr.StatusCode() == 429
+	}
+
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == 429
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
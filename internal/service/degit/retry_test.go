@@ -0,0 +1,151 @@
+package degit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	nethttp "net/http"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// testLogger records the messages logged by the service.
+type testLogger struct {
+	messages []string
+}
+
+func (l *testLogger) Print(v ...any) {
+	l.messages = append(l.messages, strings.TrimSpace(fmt.Sprint(v...)))
+}
+
+func (l *testLogger) Printf(format string, v ...any) {
+	l.messages = append(l.messages, strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (l *testLogger) Println(v ...any) {
+	l.messages = append(l.messages, strings.TrimSpace(fmt.Sprintln(v...)))
+}
+
+func TestIsTransient(t *testing.T) {
+	httpErr := func(code int) error {
+		return plumbing.NewUnexpectedError(&http.Err{Response: &nethttp.Response{StatusCode: code}})
+	}
+
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"server error":       {httpErr(503), true},
+		"too many requests":  {httpErr(429), true},
+		"client error":       {httpErr(400), false},
+		"archive status":     {&statusError{URL: "https://example.com/a.zip", StatusCode: 502}, true},
+		"archive not found":  {&statusError{URL: "https://example.com/a.zip", StatusCode: 404}, false},
+		"network":            {&net.OpError{Op: "dial", Err: errors.New("no route to host")}, true},
+		"unexpected eof":     {fmt.Errorf("read pack: %w", io.ErrUnexpectedEOF), true},
+		"connection reset":   {fmt.Errorf("fetch: %w", syscall.ECONNRESET), true},
+		"connection refused": {syscall.ECONNREFUSED, true},
+		"broken pipe":        {syscall.EPIPE, true},
+		"canceled":           {fmt.Errorf("fetch: %w", context.Canceled), false},
+		"deadline":           {context.DeadlineExceeded, false},
+		"not found":          {transport.ErrRepositoryNotFound, false},
+		"other":              {errors.New("invalid pack"), false},
+	}
+
+	for name, test := range tests {
+		if got := isTransient(test.err); got != test.want {
+			t.Errorf("%s: isTransient(%v) = %v; want %v", name, test.err, got, test.want)
+		}
+	}
+}
+
+func TestDegitService_retry(t *testing.T) {
+	transient := fmt.Errorf("fetch: %w", io.ErrUnexpectedEOF)
+	permanent := errors.New("invalid pack")
+
+	type testcase struct {
+		retries int
+		errs    []error
+		calls   int
+		err     error
+		// delays are the logged delays before each retry
+		delays []string
+	}
+
+	tests := []testcase{
+		{3, nil, 1, nil, nil},
+		{3, []error{transient, transient}, 3, nil, []string{"1ms", "2ms"}},
+		{2, []error{transient, transient, transient, transient}, 3, transient, []string{"1ms", "2ms"}},
+		{3, []error{transient, permanent, transient}, 2, permanent, []string{"1ms"}},
+		{3, []error{permanent}, 1, permanent, nil},
+		{0, []error{transient}, 1, transient, nil},
+	}
+
+	for i, test := range tests {
+		logger := &testLogger{}
+		d := NewDegitService("https://example.com/repo.git")
+		d.SetRetries(test.retries)
+		d.SetLogger(logger)
+		d.retryDelay = time.Millisecond
+
+		calls := 0
+		err := d.retry(context.Background(), func() error {
+			calls++
+			if calls <= len(test.errs) {
+				return test.errs[calls-1]
+			}
+			return nil
+		})
+		if err != test.err || calls != test.calls {
+			t.Errorf("%d: retry() = %v after %d calls; want %v after %d calls", i, err, calls, test.err, test.calls)
+		}
+
+		var delays []string
+		for _, message := range logger.messages {
+			if _, delay, ok := strings.Cut(message, "retrying in "); ok {
+				delays = append(delays, delay)
+			}
+		}
+		if !slices.Equal(delays, test.delays) {
+			t.Errorf("%d: retry() delays = %q; want %q", i, delays, test.delays)
+		}
+	}
+}
+
+func TestDegitService_retryCanceled(t *testing.T) {
+	d := NewDegitService("https://example.com/repo.git")
+	d.SetRetries(3)
+	d.retryDelay = time.Hour
+
+	// The context is canceled while waiting for the next attempt
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	calls := 0
+	err := d.retry(ctx, func() error {
+		calls++
+		return io.ErrUnexpectedEOF
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("retry() = %v after %d calls; want %v after 1 call", err, calls, context.Canceled)
+	}
+
+	// The error of an attempt is returned if the context is already canceled
+	calls = 0
+	err = d.retry(ctx, func() error {
+		calls++
+		return io.ErrUnexpectedEOF
+	})
+	if err != io.ErrUnexpectedEOF || calls != 1 {
+		t.Errorf("retry() = %v after %d calls; want %v after 1 call", err, calls, io.ErrUnexpectedEOF)
+	}
+}
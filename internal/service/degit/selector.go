@@ -0,0 +1,106 @@
+package degit
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/sotvokun/emit/internal/pkg/semver"
+)
+
+const (
+	SelectorLatest           = "latest"
+	SelectorLatestPrerelease = "latest-prerelease"
+)
+
+// selectTag returns the highest tag chosen by the selector, which is one of:
+//   - [SelectorLatest]: the highest release version tag, e.g. "v2.1.0"
+//   - [SelectorLatestPrerelease]: the highest version tag including prereleases, e.g. "v2.2.0-rc.1"
+//   - a semantic version range: the highest version tag in the range, e.g. "^2.1", "~1.2.3", ">=1 <3"
+//   - a glob pattern: the highest tag matching the pattern, e.g. "release-*"
+//
+// The returned boolean reports whether the ref is a selector at all. An error is returned if it is a selector
+// but no tag is chosen.
+func selectTag(refs []*plumbing.Reference, ref string) (*plumbing.Reference, bool, error) {
+	var match func(name string) (semver.Version, bool)
+	switch {
+	case ref == SelectorLatest:
+		match = func(name string) (semver.Version, bool) {
+			v, err := semver.Parse(name)
+			return v, err == nil && !v.IsPrerelease()
+		}
+	case ref == SelectorLatestPrerelease:
+		match = func(name string) (semver.Version, bool) {
+			v, err := semver.Parse(name)
+			return v, err == nil
+		}
+	default:
+		if r, err := semver.ParseRange(ref); err == nil && isRange(ref) {
+			match = func(name string) (semver.Version, bool) {
+				v, err := semver.Parse(name)
+				return v, err == nil && r.Contains(v)
+			}
+			break
+		}
+		if !strings.ContainsAny(ref, "*?[") {
+			return nil, false, nil
+		}
+		if _, err := path.Match(ref, ""); err != nil {
+			return nil, true, fmt.Errorf("invalid tag pattern '%s': %w", ref, err)
+		}
+		match = func(name string) (semver.Version, bool) {
+			if ok, _ := path.Match(ref, name); !ok {
+				return semver.Version{}, false
+			}
+			return looseVersion(name), true
+		}
+	}
+
+	var best *plumbing.Reference
+	var bestVersion semver.Version
+	for _, r := range refs {
+		if !r.Name().IsTag() || IsPeeled(r.Name()) {
+			continue
+		}
+		name := r.Name().Short()
+		v, ok := match(name)
+		if !ok {
+			continue
+		}
+		if best == nil {
+			best, bestVersion = r, v
+			continue
+		}
+		c := v.Compare(bestVersion)
+		if c > 0 || (c == 0 && name > best.Name().Short()) {
+			best, bestVersion = r, v
+		}
+	}
+
+	if best == nil {
+		return nil, true, fmt.Errorf("%w: no tag matches '%s'", ErrReferenceNotFound, ref)
+	}
+	return best, true, nil
+}
+
+// isRange reports whether the ref may be a semantic version range, which has a version or an operator. A bare
+// wildcard like "x" or an empty ref is a valid range matching every version, but more likely a mistyped or
+// missing reference.
+func isRange(ref string) bool {
+	return strings.ContainsAny(ref, "0123456789<>=~^")
+}
+
+// looseVersion returns the version embedded in a tag name, e.g. "1.2.0" of "release-1.2.0".
+// The zero version is returned if there is no version.
+func looseVersion(name string) semver.Version {
+	i := strings.IndexAny(name, "0123456789")
+	if i < 0 {
+		return semver.Version{}
+	}
+	v, err := semver.Parse(name[i:])
+	if err != nil {
+		return semver.Version{}
+	}
+	return v
+}
@@ -0,0 +1,221 @@
+package degit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// gitmodulesFile is the file listing the submodules of a repository.
+const gitmodulesFile = ".gitmodules"
+
+type SubmoduleMode int
+
+const (
+	// SubmodulesShallow clones the submodules with a depth of 1, it is the default mode
+	SubmodulesShallow SubmoduleMode = iota
+	// SubmodulesFull clones the submodules with their full history
+	SubmodulesFull
+	// SubmodulesNone skips all submodules
+	SubmodulesNone
+)
+
+// ParseSubmoduleMode parses the mode name: "shallow", "full" or "none".
+func ParseSubmoduleMode(name string) (SubmoduleMode, error) {
+	switch name {
+	case "shallow":
+		return SubmodulesShallow, nil
+	case "full":
+		return SubmodulesFull, nil
+	case "none":
+		return SubmodulesNone, nil
+	}
+	return 0, fmt.Errorf("invalid submodule mode '%s'", name)
+}
+
+// String returns the name of the mode, see [ParseSubmoduleMode].
+func (m SubmoduleMode) String() string {
+	switch m {
+	case SubmodulesFull:
+		return "full"
+	case SubmodulesNone:
+		return "none"
+	}
+	return "shallow"
+}
+
+// submoduleOptions controls which submodules are cloned and how they are accessed.
+type submoduleOptions struct {
+	mode SubmoduleMode
+	// paths selects the submodules to clone, all submodules are cloned if empty
+	paths []string
+	// rewrites maps URL prefixes to their replacement, like the "url.<base>.insteadOf" option of git
+	rewrites map[string]string
+	// auths maps submodule paths to their authentication methods
+	auths map[string]transport.AuthMethod
+
+	skipped []string
+}
+
+// SetSubmodules sets how the submodules are cloned. If paths are provided, only the submodules at these paths,
+// and the submodules nested in them, are cloned.
+func (d *DegitService) SetSubmodules(mode SubmoduleMode, paths ...string) {
+	d.submodules.mode = mode
+	d.submodules.paths = nil
+	for _, p := range paths {
+		d.submodules.paths = append(d.submodules.paths, path.Clean(strings.Trim(p, "/")))
+	}
+}
+
+// AddSubmoduleURLRewrite rewrites the submodule URLs starting with prefix to start with replacement instead.
+// The longest matching prefix wins, like the "url.<base>.insteadOf" option of git.
+func (d *DegitService) AddSubmoduleURLRewrite(prefix string, replacement string) {
+	if d.submodules.rewrites == nil {
+		d.submodules.rewrites = make(map[string]string)
+	}
+	d.submodules.rewrites[prefix] = replacement
+}
+
+// SetSubmoduleBasicAuth sets the basic authentication for the submodule at the path, instead of the authentication
+// of the repository.
+func (d *DegitService) SetSubmoduleBasicAuth(path string, username string, password string) {
+	if d.submodules.auths == nil {
+		d.submodules.auths = make(map[string]transport.AuthMethod)
+	}
+	d.submodules.auths[strings.Trim(path, "/")] = &http.BasicAuth{
+		Username: username,
+		Password: password,
+	}
+}
+
+// SkippedSubmodules returns the paths of the submodules skipped by the last clone.
+func (d *DegitService) SkippedSubmodules() []string {
+	return d.submodules.skipped
+}
+
+// updateSubmodules clones the submodules of the repository recursively. The prefix is the path of the
+// repository relative to the root repository.
+func (d *DegitService) updateSubmodules(ctx context.Context, repo *git.Repository, prefix string) error {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	submodules, err := worktree.Submodules()
+	if err != nil {
+		return err
+	}
+
+	for _, submodule := range submodules {
+		cfg := submodule.Config()
+		fullPath := path.Join(prefix, cfg.Path)
+		if !d.submoduleSelected(fullPath) {
+			d.log("skip submodule: %s", fullPath)
+			d.submodules.skipped = append(d.submodules.skipped, fullPath)
+			continue
+		}
+
+		if url := d.rewriteSubmoduleURL(cfg.URL); url != cfg.URL {
+			d.log("rewrite submodule url: %s -> %s", cfg.URL, url)
+			cfg.URL = url
+		}
+
+		auth := d.authMethod
+		if a, ok := d.submodules.auths[fullPath]; ok {
+			auth = a
+		}
+
+		depth := 1
+		if d.submodules.mode == SubmodulesFull {
+			depth = 0
+		}
+
+		d.log("clone submodule: %s", fullPath)
+		err := submodule.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+			Init:  true,
+			Depth: depth,
+			Auth:  auth,
+		})
+		if err != nil {
+			return fmt.Errorf("submodule '%s': %w", fullPath, err)
+		}
+
+		subrepo, err := submodule.Repository()
+		if err != nil {
+			return fmt.Errorf("submodule '%s': %w", fullPath, err)
+		}
+		if err := d.updateSubmodules(ctx, subrepo, fullPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipArchiveSubmodules records the submodules listed in the ".gitmodules" file of the extracted archive as skipped,
+// an archive does not include the files of the submodules.
+func (d *DegitService) skipArchiveSubmodules(fs billy.Filesystem) error {
+	data, err := util.ReadFile(fs, gitmodulesFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	modules := config.NewModules()
+	if err := modules.Unmarshal(data); err != nil {
+		return fmt.Errorf("invalid %s: %w", gitmodulesFile, err)
+	}
+	var paths []string
+	for _, submodule := range modules.Submodules {
+		paths = append(paths, path.Clean(strings.Trim(submodule.Path, "/")))
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		d.log("skip submodule: %s", p)
+	}
+	d.submodules.skipped = append(d.submodules.skipped, paths...)
+	return nil
+}
+
+// submoduleSelected reports whether the submodule at the path is selected by the options. A submodule is selected
+// if it is a selected path, nested in a selected path, or contains a selected path.
+func (d *DegitService) submoduleSelected(p string) bool {
+	if d.submodules.mode == SubmodulesNone {
+		return false
+	}
+	if len(d.submodules.paths) == 0 {
+		return true
+	}
+
+	for _, selected := range d.submodules.paths {
+		if p == selected || strings.HasPrefix(p, selected+"/") || strings.HasPrefix(selected, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteSubmoduleURL returns the URL rewritten by the longest matching prefix.
+func (d *DegitService) rewriteSubmoduleURL(url string) string {
+	longest := ""
+	for prefix := range d.submodules.rewrites {
+		if strings.HasPrefix(url, prefix) && len(prefix) > len(longest) {
+			longest = prefix
+		}
+	}
+	if len(longest) == 0 {
+		return url
+	}
+	return d.submodules.rewrites[longest] + strings.TrimPrefix(url, longest)
+}
@@ -0,0 +1,120 @@
+package degit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestParseSubmoduleMode(t *testing.T) {
+	tests := map[string]SubmoduleMode{
+		"shallow": SubmodulesShallow,
+		"full":    SubmodulesFull,
+		"none":    SubmodulesNone,
+	}
+	for name, want := range tests {
+		got, err := ParseSubmoduleMode(name)
+		if err != nil || got != want {
+			t.Errorf("ParseSubmoduleMode(%q) = %v, %v; want %v", name, got, err, want)
+		}
+		if got.String() != name {
+			t.Errorf("%v.String() = %q; want %q", got, got.String(), name)
+		}
+	}
+
+	for _, name := range []string{"", "Shallow", "recursive"} {
+		if _, err := ParseSubmoduleMode(name); err == nil {
+			t.Errorf("ParseSubmoduleMode(%q) should fail", name)
+		}
+	}
+}
+
+func TestDegitService_submoduleSelected(t *testing.T) {
+	type testcase struct {
+		mode  SubmoduleMode
+		paths []string
+		path  string
+		want  bool
+	}
+
+	tests := []testcase{
+		{SubmodulesShallow, nil, "lib", true},
+		{SubmodulesFull, nil, "vendor/x", true},
+		{SubmodulesNone, nil, "lib", false},
+		{SubmodulesNone, []string{"lib"}, "lib", false},
+
+		{SubmodulesShallow, []string{"lib"}, "lib", true},
+		{SubmodulesShallow, []string{"/lib/"}, "lib", true},
+		{SubmodulesShallow, []string{"lib"}, "other", false},
+		// A nested submodule is selected with its parent, and a parent is selected to reach a nested one
+		{SubmodulesShallow, []string{"lib"}, "lib/nested", true},
+		{SubmodulesShallow, []string{"lib/nested"}, "lib", true},
+		{SubmodulesShallow, []string{"lib/nested"}, "lib/other", false},
+		{SubmodulesShallow, []string{"lib/nested/deep"}, "lib/nested", true},
+		// The path prefix only matches whole path segments
+		{SubmodulesShallow, []string{"lib"}, "library", false},
+		{SubmodulesShallow, []string{"library"}, "lib", false},
+		{SubmodulesShallow, []string{"lib/nested"}, "lib/nested2", false},
+		{SubmodulesShallow, []string{"other", "vendor/x"}, "vendor", true},
+	}
+
+	for _, test := range tests {
+		d := NewDegitService("https://example.com/repo.git")
+		d.SetSubmodules(test.mode, test.paths...)
+		if got := d.submoduleSelected(test.path); got != test.want {
+			t.Errorf("submoduleSelected(%q) with %v %q = %v; want %v", test.path, test.mode, test.paths, got, test.want)
+		}
+	}
+}
+
+func TestDegitService_rewriteSubmoduleURL(t *testing.T) {
+	d := NewDegitService("https://example.com/repo.git")
+	// The longest prefix wins whatever the order of the rewrites, and a rewritten URL is not rewritten again
+	d.AddSubmoduleURLRewrite("https://github.com/org/", "https://mirror.example.com/org/")
+	d.AddSubmoduleURLRewrite("https://github.com/", "https://mirror.example.com/github/")
+	d.AddSubmoduleURLRewrite("git@github.com:", "https://github.com/")
+
+	tests := map[string]string{
+		"https://github.com/org/lib.git":  "https://mirror.example.com/org/lib.git",
+		"https://github.com/user/lib.git": "https://mirror.example.com/github/user/lib.git",
+		"git@github.com:org/lib.git":      "https://github.com/org/lib.git",
+		"https://gitlab.com/user/lib.git": "https://gitlab.com/user/lib.git",
+		"../lib.git":                      "../lib.git",
+	}
+	for url, want := range tests {
+		if got := d.rewriteSubmoduleURL(url); got != want {
+			t.Errorf("rewriteSubmoduleURL(%q) = %q; want %q", url, got, want)
+		}
+	}
+}
+
+func TestDegitService_skipArchiveSubmodules(t *testing.T) {
+	type testcase struct {
+		gitmodules string
+		want       []string
+		wantErr    bool
+	}
+
+	tests := []testcase{
+		{"", nil, false},
+		{"[submodule \"lib\"]\n\tpath = lib\n\turl = https://example.com/lib.git\n" +
+			"[submodule \"vendor/x\"]\n\tpath = /vendor/x/\n\turl = ../x.git\n", []string{"lib", "vendor/x"}, false},
+		{"[submodule \"lib\"\n", nil, true},
+	}
+
+	for _, test := range tests {
+		fs := memfs.New()
+		if len(test.gitmodules) != 0 {
+			if err := util.WriteFile(fs, ".gitmodules", []byte(test.gitmodules), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		d := NewDegitService("https://example.com/repo.git")
+		err := d.skipArchiveSubmodules(fs)
+		if got := d.SkippedSubmodules(); !reflect.DeepEqual(got, test.want) || (err != nil) != test.wantErr {
+			t.Errorf("skipArchiveSubmodules(%q) = %q, %v; want %q, error %v", test.gitmodules, got, err, test.want, test.wantErr)
+		}
+	}
+}
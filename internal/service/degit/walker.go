@@ -1,23 +1,28 @@
 package degit
 
 import (
+	"bytes"
 	"fmt"
-	"io"
 	"os"
-	"path/filepath"
 
 	"github.com/go-git/go-billy/v5"
 	"github.com/sotvokun/emit/internal/service/log"
 )
 
 type Walker struct {
-	dest   string
+	dest   Destination
 	logger log.Logger
 
 	dryMode bool
 }
 
+// NewWalker creates a walker copying the files into the directory dest.
 func NewWalker(dest string) *Walker {
+	return NewDestinationWalker(NewOSDestination(dest))
+}
+
+// NewDestinationWalker creates a walker copying the files into the destination.
+func NewDestinationWalker(dest Destination) *Walker {
 	return &Walker{
 		dest: dest,
 	}
@@ -37,49 +42,63 @@ func (w *Walker) WalkCopy(path string, fs billy.Filesystem) error {
 		return err
 	}
 
-	destFullPath := filepath.Join(w.dest, path)
-	destFullPathDir := filepath.Dir(destFullPath)
-	if err := w.do(func() error { return os.MkdirAll(destFullPathDir, os.ModePerm) }); err != nil {
-		return err
-	}
-
 	if fi.Mode()&os.ModeSymlink != 0 {
 		link, err := fs.Readlink(path)
 		if err != nil {
 			return err
 		}
 
-		w.log("create symlink: %s -> %s", destFullPath, link)
-		return w.do(func() error {
-			return os.Symlink(link, destFullPath)
-		})
+		w.log("create symlink: %s -> %s", w.name(path), link)
+		return w.do(func() error { return w.dest.Symlink(path, link) })
 	}
 
-	if _, err := os.Lstat(destFullPath); err == nil {
-		return fmt.Errorf("%s: %w", destFullPath, os.ErrExist)
+	if err := w.dest.Check(path); err != nil {
+		return fmt.Errorf("%s: %w", w.name(path), err)
 	}
 
-	w.log("create file: %s", destFullPath)
+	w.log("create file: %s", w.name(path))
 	return w.do(func() error {
-		dstFile, err := os.Create(destFullPath)
-		if err != nil {
-			return err
-		}
-		defer dstFile.Close()
-
 		srcFile, err := fs.Open(path)
 		if err != nil {
 			return err
 		}
 		defer srcFile.Close()
 
-		if _, err = io.Copy(dstFile, srcFile); err != nil {
-			return err
-		}
-		return nil
+		return w.dest.WriteFile(path, fi.Mode().Perm(), fi.Size(), srcFile)
 	})
 }
 
+// WriteFile writes a file which is not walked from a filesystem, e.g. a lock file.
+func (w *Walker) WriteFile(path string, data []byte) error {
+	if err := w.dest.Check(path); err != nil {
+		return fmt.Errorf("%s: %w", w.name(path), err)
+	}
+
+	w.log("create file: %s", w.name(path))
+	return w.do(func() error {
+		return w.dest.WriteFile(path, 0644, int64(len(data)), bytes.NewReader(data))
+	})
+}
+
+// Close completes the destination, e.g. writes the trailer of an archive.
+func (w *Walker) Close() error {
+	return w.do(w.dest.Close)
+}
+
+// Cleanup removes what the walker has written to the destination.
+func (w *Walker) Cleanup() error {
+	w.log("clean up: %s", w.name("."))
+	return w.dest.Cleanup()
+}
+
+// name returns the path of the file shown in the log messages.
+func (w *Walker) name(path string) string {
+	if dest, ok := w.dest.(*OSDestination); ok {
+		return dest.Path(path)
+	}
+	return path
+}
+
 func (w *Walker) log(format string, a ...any) {
 	if w.logger == nil {
 		return
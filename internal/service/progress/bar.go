@@ -0,0 +1,81 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	BarWidth           = 30
+	BarRefreshInterval = 100 * time.Millisecond
+)
+
+// Bar renders the progress as a single line which is redrawn in place, intended for interactive terminals.
+type Bar struct {
+	out io.Writer
+
+	mu       sync.Mutex
+	phase    string
+	lastDraw time.Time
+	lastLen  int
+}
+
+func NewBar(out io.Writer) *Bar {
+	return &Bar{out: out}
+}
+
+func (b *Bar) Phase(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.phase = name
+	b.draw(name+"...", true)
+}
+
+func (b *Bar) Message(msg string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.draw(fmt.Sprintf("%s: %s", b.phase, msg), false)
+}
+
+func (b *Bar) Files(done int, total int, bytes int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	filled := BarWidth
+	if total > 0 {
+		filled = BarWidth * done / total
+	}
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", BarWidth-filled)
+	b.draw(fmt.Sprintf("%s [%s] %d/%d, %s", b.phase, bar, done, total, FormatBytes(bytes)), done == total)
+}
+
+func (b *Bar) Done() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.lastLen > 0 {
+		fmt.Fprintln(b.out)
+		b.lastLen = 0
+	}
+}
+
+// draw redraws the line, unless the last draw happened within [BarRefreshInterval] and force is false.
+func (b *Bar) draw(line string, force bool) {
+	now := time.Now()
+	if !force && now.Sub(b.lastDraw) < BarRefreshInterval {
+		return
+	}
+	b.lastDraw = now
+
+	padding := ""
+	if len(line) < b.lastLen {
+		padding = strings.Repeat(" ", b.lastLen-len(line))
+	}
+	fmt.Fprintf(b.out, "\r%s%s", line, padding)
+	b.lastLen = len(line)
+}
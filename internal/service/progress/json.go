@@ -0,0 +1,64 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event is a progress event written by [JSON].
+type Event struct {
+	Event   string    `json:"event"`
+	Time    time.Time `json:"time"`
+	Phase   string    `json:"phase,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Done    int       `json:"done,omitempty"`
+	Total   int       `json:"total,omitempty"`
+	Bytes   int64     `json:"bytes,omitempty"`
+}
+
+// JSON renders the progress as a stream of [Event], one JSON object per line, intended for wrapper programs.
+type JSON struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	phase   string
+}
+
+func NewJSON(out io.Writer) *JSON {
+	return &JSON{encoder: json.NewEncoder(out)}
+}
+
+func (j *JSON) Phase(name string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.phase = name
+	j.emit(Event{Event: "phase", Phase: name})
+}
+
+func (j *JSON) Message(msg string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.emit(Event{Event: "message", Phase: j.phase, Message: msg})
+}
+
+func (j *JSON) Files(done int, total int, bytes int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.emit(Event{Event: "files", Phase: j.phase, Done: done, Total: total, Bytes: bytes})
+}
+
+func (j *JSON) Done() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.emit(Event{Event: "done"})
+}
+
+func (j *JSON) emit(e Event) {
+	e.Time = time.Now().UTC()
+	j.encoder.Encode(e)
+}
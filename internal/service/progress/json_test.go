@@ -0,0 +1,47 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	j := NewJSON(&buf)
+	j.Phase(PhaseFetching)
+	j.Message("Counting objects: 100%")
+	j.Files(2, 4, 1024)
+	j.Done()
+
+	want := []map[string]any{
+		{"event": "phase", "phase": "fetching objects"},
+		{"event": "message", "phase": "fetching objects", "message": "Counting objects: 100%"},
+		{"event": "files", "phase": "fetching objects", "done": 2.0, "total": 4.0, "bytes": 1024.0},
+		{"event": "done"},
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("output has %d lines; want %d:\n%s", len(lines), len(want), buf.String())
+	}
+	for i, line := range lines {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		// Each event has the time in RFC 3339
+		if s, _ := event["time"].(string); !strings.HasSuffix(s, "Z") {
+			t.Errorf("line %d: time = %v; want a UTC time", i, event["time"])
+		} else if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			t.Errorf("line %d: time: %v", i, err)
+		}
+		delete(event, "time")
+		if !reflect.DeepEqual(event, want[i]) {
+			t.Errorf("line %d = %v; want %v", i, event, want[i])
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	PlainInterval = 1 * time.Second
+)
+
+// Plain renders the progress as periodic lines, intended for logs and other non-interactive outputs.
+type Plain struct {
+	out io.Writer
+
+	mu        sync.Mutex
+	phase     string
+	lastPrint time.Time
+}
+
+func NewPlain(out io.Writer) *Plain {
+	return &Plain{out: out}
+}
+
+func (p *Plain) Phase(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.phase = name
+	p.print(name, true)
+}
+
+func (p *Plain) Message(msg string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.print(fmt.Sprintf("%s: %s", p.phase, msg), false)
+}
+
+func (p *Plain) Files(done int, total int, bytes int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.print(fmt.Sprintf("%s: %d/%d, %s", p.phase, done, total, FormatBytes(bytes)), done == total)
+}
+
+func (p *Plain) Done() {}
+
+// print prints the line, unless the last line was printed within [PlainInterval] and force is false.
+func (p *Plain) print(line string, force bool) {
+	now := time.Now()
+	if !force && now.Sub(p.lastPrint) < PlainInterval {
+		return
+	}
+	p.lastPrint = now
+	fmt.Fprintln(p.out, line)
+}
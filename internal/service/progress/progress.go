@@ -0,0 +1,82 @@
+package progress
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	PhaseListing  = "listing refs"
+	PhaseFetching = "fetching objects"
+	PhaseLFS      = "fetching lfs objects"
+	PhaseWriting  = "writing files"
+)
+
+// Reporter receives the progress of a long running operation.
+type Reporter interface {
+	// Phase is called when the operation enters a new phase.
+	Phase(name string)
+	// Message is called for each progress line sent by the remote.
+	Message(msg string)
+	// Files is called after a file is written, with the number of files done, the total, and the bytes written so far.
+	Files(done int, total int, bytes int64)
+	// Done is called when the operation finishes.
+	Done()
+}
+
+// IsTerminal reports whether the file is a character device, e.g. an interactive terminal.
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Writer adapts a [Reporter] to an [io.Writer], so it can be used as the sideband progress of go-git.
+// Each line terminated by '\n' or '\r' is sent to [Reporter.Message].
+type Writer struct {
+	reporter Reporter
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func NewWriter(reporter Reporter) *Writer {
+	return &Writer{reporter: reporter}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexAny(w.buf.Bytes(), "\r\n")
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSpace(string(w.buf.Next(i + 1)))
+		if len(line) != 0 {
+			w.reporter.Message(line)
+		}
+	}
+	return len(p), nil
+}
+
+// FormatBytes formats the size with a binary unit, e.g. "1.5 MiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
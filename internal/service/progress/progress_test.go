@@ -0,0 +1,91 @@
+package progress
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// recorder records the messages of a [Writer].
+type recorder struct {
+	messages []string
+}
+
+func (r *recorder) Phase(string)          {}
+func (r *recorder) Message(msg string)    { r.messages = append(r.messages, msg) }
+func (r *recorder) Files(int, int, int64) {}
+func (r *recorder) Done()                 {}
+
+func TestWriter(t *testing.T) {
+	r := &recorder{}
+	w := NewWriter(r)
+	for _, chunk := range []string{"Counting objects:  50%\r", "Counting obj", "ects: 100%\r\n", "\n  Done  \n", "partial"} {
+		if n, err := w.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v; want %d, nil", chunk, n, err, len(chunk))
+		}
+	}
+
+	want := []string{"Counting objects:  50%", "Counting objects: 100%", "Done"}
+	if !slices.Equal(r.messages, want) {
+		t.Errorf("messages = %q; want %q", r.messages, want)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1024:            "1.0 KiB",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+	}
+	for n, want := range tests {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q; want %q", n, got, want)
+		}
+	}
+}
+
+func TestPlain(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPlain(&buf)
+	p.Phase(PhaseFetching)
+	// The lines within the interval are skipped, except the last one of the files
+	p.Message("Counting objects: 10%")
+	p.Files(1, 2, 512)
+	p.Files(2, 2, 1536)
+	p.lastPrint = p.lastPrint.Add(-PlainInterval)
+	p.Message("Counting objects: 100%")
+	p.Done()
+
+	want := "fetching objects\n" +
+		"fetching objects: 2/2, 1.5 KiB\n" +
+		"fetching objects: Counting objects: 100%\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q; want %q", got, want)
+	}
+}
+
+func TestBar(t *testing.T) {
+	var buf bytes.Buffer
+	b := NewBar(&buf)
+	b.Phase(PhaseWriting)
+	b.Files(1, 4, 512)
+	b.lastDraw = b.lastDraw.Add(-BarRefreshInterval)
+	b.Files(2, 4, 1024)
+	b.Files(4, 4, 2048)
+	// A shorter line is padded to clear the previous one
+	b.Phase(PhaseListing)
+	b.Done()
+
+	want := "\rwriting files..." +
+		"\rwriting files [===============               ] 2/4, 1.0 KiB" +
+		"\rwriting files [==============================] 4/4, 2.0 KiB" +
+		"\rlisting refs..." + strings.Repeat(" ", 44) +
+		"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q; want %q", got, want)
+	}
+}
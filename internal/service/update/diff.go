@@ -0,0 +1,152 @@
+package update
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/sotvokun/emit/internal/pkg/diff"
+)
+
+// FileDiff is the difference between a file of the template and the file of the project.
+type FileDiff struct {
+	// Path is the slash-separated path of the file
+	Path string
+	// Status is [StatusAdded] if the file exists in the project only, [StatusDeleted] if it exists in the
+	// template only, and [StatusModified] otherwise
+	Status Status
+	// Binary reports whether either side is a binary file, the lines are empty then
+	Binary bool
+	// Template and Project are the lines of the file on each side
+	Template, Project []string
+}
+
+// Unified returns the unified diff of the file with n lines of context, from the template to the project.
+func (f *FileDiff) Unified(n int) string {
+	from, to := "a/"+f.Path, "b/"+f.Path
+	switch f.Status {
+	case StatusAdded:
+		from = "/dev/null"
+	case StatusDeleted:
+		to = "/dev/null"
+	}
+	if f.Binary {
+		return fmt.Sprintf("Binary files %s and %s differ\n", from, to)
+	}
+	return diff.Unified(from, to, f.Template, f.Project, n)
+}
+
+// Stat returns how many lines were inserted and deleted from the template to the project.
+func (f *FileDiff) Stat() (insertions int, deletions int) {
+	for _, c := range diff.Diff(f.Template, f.Project) {
+		insertions += c.B1 - c.B0
+		deletions += c.A1 - c.A0
+	}
+	return insertions, deletions
+}
+
+// Diff compares the files of the template with the files of the project, and returns the differences ordered
+// by path. The project files which are not in the template are compared only if they are in paths, e.g. the
+// files written from an older version of the template.
+func (u *UpdateService) Diff(template billy.Filesystem, paths []string) ([]FileDiff, error) {
+	templateFiles, err := readFiles(template)
+	if err != nil {
+		return nil, err
+	}
+
+	for path := range templateFiles {
+		if !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+
+	var diffs []FileDiff
+	for _, path := range paths {
+		t := templateFiles[path]
+		p, err := readFile(u.project, filepath.FromSlash(path))
+		if err != nil {
+			return nil, err
+		}
+		if p.equal(t) {
+			continue
+		}
+
+		fileDiff := FileDiff{Path: path, Status: StatusModified}
+		switch {
+		case t == nil:
+			fileDiff.Status = StatusAdded
+		case p == nil:
+			fileDiff.Status = StatusDeleted
+		}
+		if (t != nil && isBinary(t)) || (p != nil && isBinary(p)) {
+			fileDiff.Binary = true
+		} else {
+			fileDiff.Template = lines(t)
+			fileDiff.Project = lines(p)
+		}
+		diffs = append(diffs, fileDiff)
+	}
+	return diffs, nil
+}
+
+// lines splits the content of the file into lines, a missing file has no lines.
+func lines(f *file) []string {
+	if f == nil {
+		return nil
+	}
+	return diff.SplitLines(string(f.data))
+}
+
+// statBarWidth is the width of the longest bar of [Stat].
+const statBarWidth = 50
+
+// Stat formats the changed lines of the files like "git diff --stat".
+func Stat(diffs []FileDiff) string {
+	width := 0
+	for _, d := range diffs {
+		width = max(width, len(d.Path))
+	}
+
+	// The bars of the files are scaled down together if the largest change does not fit
+	stats := make([][2]int, len(diffs))
+	largest := 0
+	for i, d := range diffs {
+		stats[i][0], stats[i][1] = d.Stat()
+		largest = max(largest, stats[i][0]+stats[i][1])
+	}
+	scale := func(n int) int {
+		if largest <= statBarWidth || n == 0 {
+			return n
+		}
+		return max(n*statBarWidth/largest, 1)
+	}
+
+	var sb strings.Builder
+	insertions, deletions := 0, 0
+	for i, d := range diffs {
+		if d.Binary {
+			fmt.Fprintf(&sb, " %-*s | Bin\n", width, d.Path)
+			continue
+		}
+		ins, del := stats[i][0], stats[i][1]
+		insertions += ins
+		deletions += del
+		fmt.Fprintf(&sb, " %-*s | %d %s%s\n", width, d.Path, ins+del,
+			strings.Repeat("+", scale(ins)), strings.Repeat("-", scale(del)))
+	}
+
+	fmt.Fprintf(&sb, " %s changed, %s(+), %s(-)\n",
+		plural(len(diffs), "file"), plural(insertions, "insertion"), plural(deletions, "deletion"))
+	return sb.String()
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,323 @@
+// Package update applies the changes between two versions of a template to a project created from it.
+package update
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/sotvokun/emit/internal/pkg/diff"
+	"github.com/sotvokun/emit/internal/service/log"
+)
+
+// ConflictStyle is how the conflicting changes of a text file are written.
+type ConflictStyle string
+
+const (
+	// ConflictMarkers writes both sides of a conflict into the file between conflict markers
+	ConflictMarkers ConflictStyle = "markers"
+	// ConflictReject keeps the project side in the file and writes the template side into a ".rej" file
+	ConflictReject ConflictStyle = "rej"
+
+	// RejectSuffix is appended to the path of a file to name its ".rej" file
+	RejectSuffix = ".rej"
+)
+
+func ParseConflictStyle(style string) (ConflictStyle, error) {
+	switch ConflictStyle(style) {
+	case ConflictMarkers, ConflictReject:
+		return ConflictStyle(style), nil
+	}
+	return "", fmt.Errorf("invalid conflict style '%s', expect markers or rej", style)
+}
+
+// Status is the change of a project file made by an update.
+type Status byte
+
+const (
+	StatusAdded    Status = 'A'
+	StatusModified Status = 'M'
+	StatusDeleted  Status = 'D'
+	StatusConflict Status = 'C'
+)
+
+// Change is a change of a project file made by an update.
+type Change struct {
+	// Path is the slash-separated path of the file
+	Path   string
+	Status Status
+	// Detail describes how the file was changed, e.g. "merged", or why it is in conflict
+	Detail string
+	// Written reports whether the file is written by the update, e.g. with the merged lines or the conflict
+	// markers, it is false for the deleted files and the conflicts keeping the project version
+	Written bool
+}
+
+// UpdateService merges the changes from the old version of a template to the new version into the project files.
+type UpdateService struct {
+	project billy.Filesystem
+
+	conflictStyle ConflictStyle
+	projectLabel  string
+	templateLabel string
+
+	logger  log.Logger
+	dryMode bool
+}
+
+func NewUpdateService(project billy.Filesystem) *UpdateService {
+	return &UpdateService{
+		project:       project,
+		conflictStyle: ConflictMarkers,
+		projectLabel:  "project",
+		templateLabel: "template",
+	}
+}
+
+func (u *UpdateService) SetLogger(logger log.Logger) {
+	u.logger = logger
+}
+
+func (u *UpdateService) SetDryMode(dryMode bool) {
+	u.dryMode = dryMode
+}
+
+func (u *UpdateService) SetConflictStyle(style ConflictStyle) {
+	u.conflictStyle = style
+}
+
+// SetLabels sets the names of the project and the template shown by the conflict markers.
+func (u *UpdateService) SetLabels(project, template string) {
+	u.projectLabel = project
+	u.templateLabel = template
+}
+
+// file is the content of a file on one side, the target for symlinks.
+type file struct {
+	data []byte
+	mode os.FileMode
+}
+
+func (f *file) equal(other *file) bool {
+	if f == nil || other == nil {
+		return f == other
+	}
+	return f.mode&os.ModeSymlink == other.mode&os.ModeSymlink && bytes.Equal(f.data, other.data)
+}
+
+// Update merges the changes from the files of base to the files of template into the project, and returns the
+// changes of the project files ordered by path. The project files which the template does not know about are
+// never touched. A file changed on both sides is merged by lines, the conflicts are written by the conflict
+// style, and binary files in conflict keep the project side.
+func (u *UpdateService) Update(base, template billy.Filesystem) ([]Change, error) {
+	baseFiles, err := readFiles(base)
+	if err != nil {
+		return nil, err
+	}
+	templateFiles, err := readFiles(template)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for path := range baseFiles {
+		paths = append(paths, path)
+	}
+	for path := range templateFiles {
+		if _, ok := baseFiles[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+
+	var changes []Change
+	for _, path := range paths {
+		b, t := baseFiles[path], templateFiles[path]
+		if b.equal(t) {
+			continue
+		}
+		p, err := readFile(u.project, filepath.FromSlash(path))
+		if err != nil {
+			return nil, err
+		}
+
+		change, err := u.update(path, b, p, t)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	return changes, nil
+}
+
+// update applies the change of the template file from b to t to the project file p, a nil file does not exist.
+// No change is returned if the project file is already up to date.
+func (u *UpdateService) update(path string, b, p, t *file) (*Change, error) {
+	switch {
+	case p.equal(t):
+		return nil, nil
+	case p.equal(b):
+		if t == nil {
+			u.log("delete file: %s", path)
+			return &Change{Path: path, Status: StatusDeleted}, u.do(func() error { return u.remove(path) })
+		}
+		status := StatusModified
+		if p == nil {
+			status = StatusAdded
+		}
+		u.log("write file: %s", path)
+		return &Change{Path: path, Status: status, Written: true}, u.do(func() error { return u.write(path, t) })
+	case t == nil:
+		return &Change{Path: path, Status: StatusConflict, Detail: "deleted in the template, modified in the project"}, nil
+	case p == nil:
+		return &Change{Path: path, Status: StatusConflict, Detail: "modified in the template, deleted in the project"}, nil
+	case (p.mode|t.mode)&os.ModeSymlink != 0:
+		return &Change{Path: path, Status: StatusConflict, Detail: "symlink modified on both sides, kept the project version"}, nil
+	case isBinary(p) || isBinary(t) || (b != nil && isBinary(b)):
+		return &Change{Path: path, Status: StatusConflict, Detail: "binary file modified on both sides, kept the project version"}, nil
+	}
+
+	var baseLines []string
+	if b != nil {
+		baseLines = diff.SplitLines(string(b.data))
+	}
+	regions := diff.Merge3(baseLines, diff.SplitLines(string(p.data)), diff.SplitLines(string(t.data)))
+	conflicts := diff.Conflicts(regions)
+	if conflicts == 0 {
+		u.log("merge file: %s", path)
+		merged := &file{data: []byte(diff.Ours(regions)), mode: p.mode}
+		return &Change{Path: path, Status: StatusModified, Detail: "merged", Written: true}, u.do(func() error { return u.write(path, merged) })
+	}
+
+	detail := fmt.Sprintf("%d conflicting hunks", conflicts)
+	if conflicts == 1 {
+		detail = "1 conflicting hunk"
+	}
+	if u.conflictStyle == ConflictReject {
+		u.log("write rejected hunks: %s%s", path, RejectSuffix)
+		merged := &file{data: []byte(diff.Ours(regions)), mode: p.mode}
+		reject := &file{data: []byte(rejects(path, regions)), mode: 0644}
+		change := &Change{Path: path, Status: StatusConflict, Detail: detail + ", see " + path + RejectSuffix, Written: true}
+		return change, u.do(func() error {
+			if err := u.write(path, merged); err != nil {
+				return err
+			}
+			return u.write(path+RejectSuffix, reject)
+		})
+	}
+
+	u.log("write conflict markers: %s", path)
+	merged := &file{data: []byte(diff.Markers(regions, u.projectLabel, u.templateLabel)), mode: p.mode}
+	return &Change{Path: path, Status: StatusConflict, Detail: detail, Written: true}, u.do(func() error { return u.write(path, merged) })
+}
+
+// rejects formats the template side of the conflicts as a unified diff against the base.
+func rejects(path string, regions []diff.Region) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", path, path)
+	for _, hunk := range diff.Rejects(regions) {
+		sb.WriteString(hunk.String())
+	}
+	return sb.String()
+}
+
+// isBinary reports whether the file looks binary like Git does, by a NUL byte in its first 8000 bytes.
+func isBinary(f *file) bool {
+	data := f.data
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) != -1
+}
+
+// write writes the file into the project, replacing an existing file.
+func (u *UpdateService) write(path string, f *file) error {
+	name := filepath.FromSlash(path)
+	if err := u.project.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	if f.mode&os.ModeSymlink != 0 {
+		if err := u.project.Remove(name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return u.project.Symlink(string(f.data), name)
+	}
+	if fi, err := u.project.Lstat(name); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		if err := u.project.Remove(name); err != nil {
+			return err
+		}
+	}
+	return util.WriteFile(u.project, name, f.data, f.mode.Perm())
+}
+
+func (u *UpdateService) remove(path string) error {
+	return u.project.Remove(filepath.FromSlash(path))
+}
+
+// readFiles reads all the files of fs by their slash-separated paths.
+func readFiles(fs billy.Filesystem) (map[string]*file, error) {
+	files := make(map[string]*file)
+	err := util.Walk(fs, "", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := readFile(fs, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(path)] = f
+		return nil
+	})
+	return files, err
+}
+
+// readFile reads the file of fs, nil is returned if it does not exist.
+func readFile(fs billy.Filesystem, path string) (*file, error) {
+	fi, err := fs.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return nil, fmt.Errorf("%s: is a directory", path)
+	}
+
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := fs.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		return &file{data: []byte(target), mode: fi.Mode()}, nil
+	}
+	data, err := util.ReadFile(fs, path)
+	if err != nil {
+		return nil, err
+	}
+	return &file{data: data, mode: fi.Mode()}, nil
+}
+
+func (u *UpdateService) log(format string, a ...any) {
+	if u.logger == nil {
+		return
+	}
+	u.logger.Printf(format+"\n", a...)
+}
+
+func (u *UpdateService) do(fn func() error) error {
+	if u.dryMode {
+		return nil
+	}
+	return fn()
+}
@@ -0,0 +1,191 @@
+package update
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func newFilesystem(t *testing.T, files map[string]string) billy.Filesystem {
+	t.Helper()
+	fs := memfs.New()
+	for path, content := range files {
+		if err := util.WriteFile(fs, path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fs
+}
+
+func TestUpdate(t *testing.T) {
+	base := map[string]string{
+		"unchanged.txt": "a\n",
+		"updated.txt":   "a\n",
+		"merged.txt":    "1\n2\n3\n4\n5\n",
+		"conflict.txt":  "a\nb\nc\n",
+		"deleted.txt":   "a\n",
+		"kept.txt":      "a\n",
+		"removed.txt":   "a\n",
+	}
+	template := map[string]string{
+		"unchanged.txt": "a\n",
+		"updated.txt":   "b\n",
+		"merged.txt":    "1\n2\n3\n4\nfive\n",
+		"conflict.txt":  "a\ntemplate\nc\n",
+		"added.txt":     "new\n",
+		"removed.txt":   "b\n",
+	}
+	project := map[string]string{
+		"unchanged.txt": "project\n",
+		"updated.txt":   "a\n",
+		"merged.txt":    "one\n2\n3\n4\n5\n",
+		"conflict.txt":  "a\nproject\nc\n",
+		"deleted.txt":   "a\n",
+		"kept.txt":      "project\n",
+		"own.txt":       "project\n",
+	}
+
+	type result struct {
+		status  Status
+		content string
+		written bool
+	}
+	want := map[string]result{
+		"added.txt":    {StatusAdded, "new\n", true},
+		"conflict.txt": {StatusConflict, "a\n<<<<<<< project\nproject\n=======\ntemplate\n>>>>>>> template\nc\n", true},
+		"deleted.txt":  {StatusDeleted, "", false},
+		"kept.txt":     {StatusConflict, "project\n", false},
+		"merged.txt":   {StatusModified, "one\n2\n3\n4\nfive\n", true},
+		"removed.txt":  {StatusConflict, "", false},
+		"updated.txt":  {StatusModified, "b\n", true},
+	}
+
+	fs := newFilesystem(t, project)
+	service := NewUpdateService(fs)
+	changes, err := service.Update(newFilesystem(t, base), newFilesystem(t, template))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changes) != len(want) {
+		t.Errorf("Update() = %d changes; want %d: %v", len(changes), len(want), changes)
+	}
+	for i, change := range changes {
+		if i > 0 && changes[i-1].Path >= change.Path {
+			t.Errorf("Update() changes are not ordered by path: %s, %s", changes[i-1].Path, change.Path)
+		}
+		w, ok := want[change.Path]
+		if !ok {
+			t.Errorf("Update() changed %s unexpectedly", change.Path)
+			continue
+		}
+		if change.Status != w.status || change.Written != w.written {
+			t.Errorf("Update() status of %s = %c, written %v; want %c, written %v", change.Path, change.Status, change.Written, w.status, w.written)
+		}
+		data, err := util.ReadFile(fs, change.Path)
+		if err != nil && len(w.content) != 0 {
+			t.Errorf("read %s: %v", change.Path, err)
+		}
+		if string(data) != w.content {
+			t.Errorf("content of %s = %q; want %q", change.Path, data, w.content)
+		}
+	}
+
+	for path, content := range map[string]string{"unchanged.txt": "project\n", "own.txt": "project\n"} {
+		if data, _ := util.ReadFile(fs, path); string(data) != content {
+			t.Errorf("content of %s = %q; want %q", path, data, content)
+		}
+	}
+}
+
+func TestUpdate_reject(t *testing.T) {
+	fs := newFilesystem(t, map[string]string{"file.txt": "a\nproject\nc\n"})
+	service := NewUpdateService(fs)
+	service.SetConflictStyle(ConflictReject)
+
+	_, err := service.Update(
+		newFilesystem(t, map[string]string{"file.txt": "a\nb\nc\n"}),
+		newFilesystem(t, map[string]string{"file.txt": "a\ntemplate\nc\n"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := util.ReadFile(fs, "file.txt"); string(data) != "a\nproject\nc\n" {
+		t.Errorf("content of file.txt = %q; want the project version", data)
+	}
+	want := "--- file.txt\n+++ file.txt\n@@ -2 +2 @@\n-b\n+template\n"
+	if data, _ := util.ReadFile(fs, "file.txt"+RejectSuffix); string(data) != want {
+		t.Errorf("content of file.txt.rej = %q; want %q", data, want)
+	}
+}
+
+func TestUpdate_dryMode(t *testing.T) {
+	fs := newFilesystem(t, map[string]string{"file.txt": "a\n"})
+	service := NewUpdateService(fs)
+	service.SetDryMode(true)
+
+	changes, err := service.Update(
+		newFilesystem(t, map[string]string{"file.txt": "a\n"}),
+		newFilesystem(t, map[string]string{"file.txt": "b\n", "new.txt": "new\n"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Errorf("Update() = %v; want 2 changes", changes)
+	}
+	if data, _ := util.ReadFile(fs, "file.txt"); string(data) != "a\n" {
+		t.Errorf("content of file.txt = %q; want unchanged in the dry mode", data)
+	}
+	if _, err := fs.Lstat("new.txt"); err == nil {
+		t.Errorf("new.txt is written in the dry mode")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	fs := newFilesystem(t, map[string]string{
+		"same.txt":    "a\n",
+		"changed.txt": "a\nproject\n",
+		"old.txt":     "a\n",
+		"own.txt":     "a\n",
+	})
+	template := newFilesystem(t, map[string]string{
+		"same.txt":    "a\n",
+		"changed.txt": "a\nb\n",
+		"missing.txt": "a\n",
+	})
+
+	diffs, err := NewUpdateService(fs).Diff(template, []string{"old.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		path   string
+		status Status
+	}{
+		{"changed.txt", StatusModified},
+		{"missing.txt", StatusDeleted},
+		{"old.txt", StatusAdded},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("Diff() = %v; want %d files", diffs, len(want))
+	}
+	for i, w := range want {
+		if diffs[i].Path != w.path || diffs[i].Status != w.status {
+			t.Errorf("Diff()[%d] = %c %s; want %c %s", i, diffs[i].Status, diffs[i].Path, w.status, w.path)
+		}
+	}
+
+	wantUnified := "--- a/changed.txt\n+++ b/changed.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+project\n"
+	if got := diffs[0].Unified(3); got != wantUnified {
+		t.Errorf("Unified() = %q; want %q", got, wantUnified)
+	}
+	wantStat := " changed.txt | 2 +-\n missing.txt | 1 -\n old.txt     | 1 +\n 3 files changed, 2 insertions(+), 2 deletions(-)\n"
+	if got := Stat(diffs); got != wantStat {
+		t.Errorf("Stat() = %q; want %q", got, wantStat)
+	}
+}
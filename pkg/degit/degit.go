@@ -0,0 +1,275 @@
+// Package degit downloads the files of a Git repository without its history.
+//
+// The source can be a remote Git repository, a local working tree, a bare repository, a bundle file, or a
+// .tar.gz, .tar.zst or .zip archive. The files are written into a [Destination], e.g. a directory, an archive,
+// or an in-memory filesystem:
+//
+//	d, err := degit.New(degit.Options{
+//		Remote:      "https://github.com/user/repo.git",
+//		Ref:         "v1.2.0",
+//		Destination: degit.NewOSDestination("new-project"),
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	_, err = d.Clone(ctx)
+package degit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/sotvokun/emit/internal/service/degit"
+	"github.com/sotvokun/emit/internal/service/log"
+	"github.com/sotvokun/emit/internal/service/progress"
+)
+
+var (
+	// ErrRefNotFound is returned when no reference matches the requested ref.
+	ErrRefNotFound = degit.ErrReferenceNotFound
+	// ErrDestinationExists is returned when a file to write already exists in the destination.
+	ErrDestinationExists = degit.ErrDestinationExists
+	// ErrAuth is returned when the remote rejects the authentication, or requires one.
+	ErrAuth = degit.ErrAuth
+)
+
+type (
+	// Resolution is the concrete reference resolved from the requested ref.
+	Resolution = degit.Resolution
+	// Lock records where the files of a destination came from, see [Options.LockFile].
+	Lock = degit.Lock
+	// AmbiguousReferenceError is returned when the requested name is both a branch and a tag, or the requested
+	// commit hash prefix matches more than one commit.
+	AmbiguousReferenceError = degit.AmbiguousReferenceError
+
+	// Logger receives the verbose messages, e.g. a [log.Logger] of the standard library.
+	Logger = log.Logger
+	// Reporter receives the progress of a clone.
+	Reporter = progress.Reporter
+
+	// Destination receives the copied files.
+	Destination = degit.Destination
+	// OSDestination writes the files into a directory of the OS filesystem.
+	OSDestination = degit.OSDestination
+	// FilesystemDestination writes the files into a billy.Filesystem.
+	FilesystemDestination = degit.FilesystemDestination
+	// ArchiveDestination writes the files as a reproducible tar or zip archive.
+	ArchiveDestination = degit.ArchiveDestination
+
+	// Mode is how a Git remote is fetched.
+	Mode = degit.Mode
+	// SubmoduleMode is how the submodules are cloned.
+	SubmoduleMode = degit.SubmoduleMode
+	// LFSMode is how the Git LFS files are handled.
+	LFSMode = degit.LFSMode
+)
+
+const (
+	PeeledSuffix    = degit.PeeledSuffix
+	DefaultLockFile = degit.DefaultLockFile
+	LockVersion     = degit.LockVersion
+
+	ModeGit = degit.ModeGit
+	ModeTar = degit.ModeTar
+
+	SubmodulesShallow = degit.SubmodulesShallow
+	SubmodulesFull    = degit.SubmodulesFull
+	SubmodulesNone    = degit.SubmodulesNone
+
+	LFSFetch   = degit.LFSFetch
+	LFSPointer = degit.LFSPointer
+	LFSSkip    = degit.LFSSkip
+
+	ArchiveTar    = degit.ArchiveTar
+	ArchiveTarGz  = degit.ArchiveTarGz
+	ArchiveTarZst = degit.ArchiveTarZst
+	ArchiveZip    = degit.ArchiveZip
+)
+
+var (
+	NewOSDestination         = degit.NewOSDestination
+	NewFilesystemDestination = degit.NewFilesystemDestination
+	NewMemoryDestination     = degit.NewMemoryDestination
+	NewArchiveDestination    = degit.NewArchiveDestination
+
+	// IsPeeled reports whether the reference name is a peeled annotated tag returned by [Degit.References].
+	IsPeeled = degit.IsPeeled
+
+	ParseMode          = degit.ParseMode
+	ParseSubmoduleMode = degit.ParseSubmoduleMode
+	ParseLFSMode       = degit.ParseLFSMode
+
+	// ArchiveFormat returns the format of the archive by the extension of its path or URL, an empty string is
+	// returned if the source is not an archive.
+	ArchiveFormat = degit.ArchiveFormat
+	// ReadLock reads the lock file at the path of the OS filesystem.
+	ReadLock = degit.ReadLock
+	// ParseLock parses the content of a lock file.
+	ParseLock = degit.ParseLock
+	// ValidateLockFile checks that the slash-separated path of a lock file is relative and within the destination.
+	ValidateLockFile = degit.ValidateLockFile
+	// HashFile returns the hash of the file in fs as it is recorded in a lock file, e.g. "sha256:...".
+	HashFile = degit.HashFile
+	// NormalizeRemote returns the remote without credentials as it is recorded in a lock file.
+	NormalizeRemote = degit.NormalizeRemote
+	// LocalPath returns the path of the remote on the local filesystem, false is returned if the remote is
+	// neither a "file://" URL nor an absolute path or a path starting with "./" or "../".
+	LocalPath = degit.LocalPath
+)
+
+// Auth is the authentication of a remote.
+type Auth struct {
+	// Username is the username of the basic authentication, or the SSH user which is "git" by default
+	Username string
+	// Password is the password or token of the basic authentication, or the passphrase of the identity file
+	Password string
+	// IdentityFile is the path to the private key of the SSH authentication
+	IdentityFile string
+}
+
+// Options configures a [Degit].
+type Options struct {
+	// Remote is the URL of a Git repository, a local path to a working tree, a bare repository or a bundle file,
+	// or an archive as a local path or an HTTP URL
+	Remote string
+	// Ref is a branch, tag, commit hash, or a selector like "latest" or "^1.2". HEAD is used if it is empty.
+	Ref string
+	// Auth is the authentication of the remote, no authentication is used if it is nil
+	Auth *Auth
+
+	// Include and Exclude are gitignore-style patterns selecting the files to copy, e.g. "src/" or "*.md".
+	// A file is copied if it matches Include, or Include is empty, and it does not match Exclude.
+	Include []string
+	Exclude []string
+
+	// Destination receives the files, it is required by [Degit.Clone]
+	Destination Destination
+	// DryRun walks the files without writing them
+	DryRun bool
+	// LockFile is the path of the lock file written into the destination after the files, relative to the
+	// destination, e.g. [DefaultLockFile]. No lock file is written if it is empty.
+	LockFile string
+
+	Mode       Mode
+	Submodules SubmoduleMode
+	// SubmodulePaths limits the cloned submodules to the paths, all submodules are cloned if it is empty
+	SubmodulePaths []string
+	// SubmoduleURLRewrites rewrites the submodule URLs starting with a key to start with its value
+	SubmoduleURLRewrites map[string]string
+	// SubmoduleAuth is the basic authentication of the submodules by path, the identity file is not supported
+	SubmoduleAuth map[string]Auth
+	LFS           LFSMode
+	// IncludeUncommitted copies the current state of a local working tree instead of a reference
+	IncludeUncommitted bool
+
+	// Retries is how many times a network operation is retried after a transient failure
+	Retries  int
+	Logger   Logger
+	Progress Reporter
+}
+
+// Degit clones the files of a repository configured by [Options].
+type Degit struct {
+	options Options
+	service *degit.DegitService
+}
+
+// New creates a [Degit] with the options.
+func New(options Options) (*Degit, error) {
+	if len(options.Remote) == 0 {
+		return nil, fmt.Errorf("missing remote")
+	}
+	if options.IncludeUncommitted && len(options.Ref) != 0 {
+		return nil, fmt.Errorf("uncommitted changes cannot be included with a ref")
+	}
+	if options.Retries < 0 {
+		return nil, fmt.Errorf("retries must not be negative")
+	}
+	if len(options.LockFile) != 0 {
+		if err := degit.ValidateLockFile(options.LockFile); err != nil {
+			return nil, err
+		}
+	}
+
+	service, err := newService(options.Remote, options.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	service.SetRetries(options.Retries)
+	if options.Logger != nil {
+		service.SetLogger(options.Logger)
+	}
+	if options.Progress != nil {
+		service.SetProgress(options.Progress)
+	}
+	service.SetMode(options.Mode)
+	service.SetSubmodules(options.Submodules, options.SubmodulePaths...)
+	for prefix, replacement := range options.SubmoduleURLRewrites {
+		service.AddSubmoduleURLRewrite(prefix, replacement)
+	}
+	for path, auth := range options.SubmoduleAuth {
+		service.SetSubmoduleBasicAuth(path, auth.Username, auth.Password)
+	}
+	service.SetLFS(options.LFS)
+	service.SetIncludeUncommitted(options.IncludeUncommitted)
+	service.SetFilters(options.Include, options.Exclude)
+	service.SetLockFile(options.LockFile)
+
+	return &Degit{
+		options: options,
+		service: service,
+	}, nil
+}
+
+func newService(remote string, auth *Auth) (*degit.DegitService, error) {
+	switch {
+	case auth == nil:
+		return degit.NewDegitService(remote), nil
+	case len(auth.IdentityFile) != 0:
+		username := auth.Username
+		if len(username) == 0 {
+			username = "git"
+		}
+		return degit.NewDegitServiceWithPublicKey(remote, auth.IdentityFile, username, auth.Password)
+	}
+	return degit.NewDegitServiceWithBasicAuth(remote, auth.Username, auth.Password), nil
+}
+
+// References returns the references of the remote. Each annotated tag is followed by a peeled reference, which
+// has the "^{}" suffix and points to the tagged commit.
+func (d *Degit) References(ctx context.Context) ([]*plumbing.Reference, error) {
+	return d.service.ListContext(ctx)
+}
+
+// Resolve resolves the ref of the options against the references of the remote.
+func (d *Degit) Resolve(ctx context.Context) (*Resolution, error) {
+	return d.service.ResolveContext(ctx, d.options.Ref)
+}
+
+// Clone resolves the ref of the options and copies the files into the destination.
+func (d *Degit) Clone(ctx context.Context) (*Resolution, error) {
+	resolution, err := d.Resolve(ctx)
+	if err != nil {
+		if d.options.Progress != nil {
+			d.options.Progress.Done()
+		}
+		return nil, err
+	}
+	return resolution, d.CloneResolution(ctx, resolution)
+}
+
+// CloneResolution copies the files of a reference resolved by [Degit.Resolve] into the destination.
+// The files written before a failure or a cancellation of ctx are removed.
+func (d *Degit) CloneResolution(ctx context.Context, resolution *Resolution) error {
+	if d.options.Destination == nil {
+		return fmt.Errorf("missing destination")
+	}
+	return d.service.CloneResolutionContext(ctx, resolution, d.options.Destination, d.options.DryRun)
+}
+
+// SkippedSubmodules returns the paths of the submodules which were not cloned by the last clone.
+func (d *Degit) SkippedSubmodules() []string {
+	return d.service.SkippedSubmodules()
+}
@@ -0,0 +1,201 @@
+package degit_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/sotvokun/emit/pkg/degit"
+)
+
+// createRepository creates a local repository with a tagged commit of the files.
+func createRepository(files map[string]string) (string, func()) {
+	dir, err := os.MkdirTemp("", "degit-example")
+	if err != nil {
+		panic(err)
+	}
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		panic(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		panic(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			panic(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			panic(err)
+		}
+		if _, err := worktree.Add(name); err != nil {
+			panic(err)
+		}
+	}
+	signature := &object.Signature{Name: "emit", Email: "emit@example.com", When: time.Unix(0, 0)}
+	commit, err := worktree.Commit("initial", &git.CommitOptions{Author: signature})
+	if err != nil {
+		panic(err)
+	}
+	if _, err := repo.CreateTag("v1.0.0", commit, nil); err != nil {
+		panic(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func Example() {
+	remote, cleanup := createRepository(map[string]string{
+		"README.md":   "# Template\n",
+		"src/main.go": "package main\n",
+	})
+	defer cleanup()
+
+	dest := degit.NewMemoryDestination()
+	d, err := degit.New(degit.Options{
+		Remote:      remote,
+		Ref:         "latest",
+		Destination: dest,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	resolution, err := d.Clone(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	readme, err := util.ReadFile(dest.Filesystem(), "README.md")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(resolution.Reference.Name())
+	fmt.Print(string(readme))
+	// Output:
+	// refs/tags/v1.0.0
+	// # Template
+}
+
+func ExampleOptions_filters() {
+	remote, cleanup := createRepository(map[string]string{
+		"README.md":        "# Template\n",
+		"src/main.go":      "package main\n",
+		"src/main_test.go": "package main\n",
+	})
+	defer cleanup()
+
+	dest := degit.NewMemoryDestination()
+	d, err := degit.New(degit.Options{
+		Remote:      remote,
+		Include:     []string{"src/"},
+		Exclude:     []string{"*_test.go"},
+		Destination: dest,
+	})
+	if err != nil {
+		panic(err)
+	}
+	if _, err := d.Clone(context.Background()); err != nil {
+		panic(err)
+	}
+
+	files, err := util.Glob(dest.Filesystem(), "*/*")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(files)
+	// Output:
+	// [src/main.go]
+}
+
+func ExampleNewArchiveDestination() {
+	remote, cleanup := createRepository(map[string]string{
+		"README.md":   "# Template\n",
+		"src/main.go": "package main\n",
+	})
+	defer cleanup()
+
+	var buf bytes.Buffer
+	dest, err := degit.NewArchiveDestination(&buf, degit.ArchiveTarGz)
+	if err != nil {
+		panic(err)
+	}
+	d, err := degit.New(degit.Options{Remote: remote, Destination: dest})
+	if err != nil {
+		panic(err)
+	}
+	if _, err := d.Clone(context.Background()); err != nil {
+		panic(err)
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		panic(err)
+	}
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			panic(err)
+		}
+		fmt.Println(header.Name, header.ModTime.UTC().Format(time.DateOnly))
+	}
+	// Output:
+	// README.md 1980-01-01
+	// src/ 1980-01-01
+	// src/main.go 1980-01-01
+}
+
+func ExampleErrRefNotFound() {
+	remote, cleanup := createRepository(map[string]string{
+		"README.md": "# Template\n",
+	})
+	defer cleanup()
+
+	d, err := degit.New(degit.Options{
+		Remote:      remote,
+		Ref:         "v2.0.0",
+		Destination: degit.NewMemoryDestination(),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = d.Clone(context.Background())
+	fmt.Println(errors.Is(err, degit.ErrRefNotFound))
+	// Output:
+	// true
+}
+
+func ExampleErrDestinationExists() {
+	remote, cleanup := createRepository(map[string]string{
+		"README.md": "# Template\n",
+	})
+	defer cleanup()
+
+	dest := degit.NewMemoryDestination()
+	if err := util.WriteFile(dest.Filesystem(), "README.md", []byte("# Existing\n"), 0644); err != nil {
+		panic(err)
+	}
+	d, err := degit.New(degit.Options{Remote: remote, Destination: dest})
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = d.Clone(context.Background())
+	fmt.Println(errors.Is(err, degit.ErrDestinationExists))
+	// Output:
+	// true
+}